@@ -54,20 +54,22 @@ func (c *ContentBlock) TextContent() string {
 func (a *Accumulator) Complete(ctx context.Context, req *claude.MessageRequest, options ...CompleteOption) (*claude.MessageStart, error) {
 	req.Stream = true
 
-	mr, err := a.client.Message(ctx, req)
-	if err != nil {
-		return nil, err
-	}
-
 	var opts completeOptions
 	for _, opt := range options {
 		opt.set(&opts)
 	}
 
+	// The delta channel is closed on every return, including a failed
+	// request, so callers can always wait for their reader to finish.
 	if opts.contentBlockDeltaChan != nil {
 		defer close(opts.contentBlockDeltaChan)
 	}
 
+	mr, err := a.client.Message(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
 	contentBlocks := make([]ContentBlock, 0, 2)
 
 	var (
@@ -23,6 +23,9 @@ var (
 	listModels   bool
 	files        []string
 	punFlag      bool
+	promptFlag   string
+	autoCommit   bool
+	noTools      bool
 )
 var rootCmd = &cobra.Command{
 	Use:   "code-buddy",
@@ -48,21 +51,7 @@ var rootCmd = &cobra.Command{
 			os.Exit(0)
 		}
 
-		var apiKey string
-
-		conf, err := config.LoadConfig()
-		if err != nil && err != config.NoConfigErr {
-			log.Fatalf("Read config file err: %s", err)
-		}
-
-		apiKey = conf.AnthropicApiKey
-
-		if apiKey == "" {
-			apiKey = os.Getenv("CLAUDE_API_KEY")
-			if apiKey == "" {
-				log.Fatalf("No API key found in config file %s or environment variable CLAUDE_API_KEY", config.ConfigFilePath())
-			}
-		}
+		conf, apiKey := loadConfig()
 
 		if modelFlag == "" && conf.Model != "" {
 			modelFlag = conf.Model
@@ -70,11 +59,44 @@ var rootCmd = &cobra.Command{
 			modelFlag = claude.Claude3Dot7SonnetLatest
 		}
 
+		if promptFlag == "" {
+			promptFlag = conf.DefaultPrompt
+		}
+
 		r := interactive.Runner{
-			APIKey:        apiKey,
-			Model:         modelFlag,
-			CustomPrompts: conf.CustomPrompts,
-			PunMode:       punFlag,
+			APIKey:          apiKey,
+			Model:           modelFlag,
+			CustomPrompts:   conf.CustomPrompts,
+			Commands:        conf.Commands,
+			Hooks:           conf.Hooks,
+			MCPServers:      conf.MCPServers,
+			Prompt:          promptFlag,
+			PunMode:         punFlag,
+			CatMaxLines:     conf.CatMaxLines,
+			TreeDepth:       conf.TreeDepth,
+			TreeTokenBudget: conf.TreeTokenBudget,
+
+			RepoMapTokenBudget: conf.RepoMapTokenBudget,
+			DisableRepoMap:     conf.DisableRepoMap,
+
+			DisableGitContext: conf.DisableGitContext,
+			GitStatusMaxLines: conf.GitStatusMaxLines,
+			GitRecentCommits:  conf.GitRecentCommits,
+
+			AutoCommit:       autoCommit || conf.AutoCommit,
+			AutoCommitBranch: conf.AutoCommitBranch,
+
+			DisableTools: noTools || conf.DisableTools,
+
+			DelegateMaxTurns:  conf.DelegateMaxTurns,
+			DelegateMaxTokens: conf.DelegateMaxTokens,
+
+			PostEditChecks: interactive.PostEditChecks{
+				Gofmt:     conf.PostEditGofmt,
+				Goimports: conf.PostEditGoimports,
+				Vet:       conf.PostEditVet,
+				Build:     conf.PostEditBuild,
+			},
 		}
 
 		if cmd.Flags().Changed("system-prompt") {
@@ -96,21 +118,59 @@ var rootCmd = &cobra.Command{
 			r.DebugLogger.Debug("start debug logger")
 		}
 
-		err = r.Run(ctx)
+		err := r.Run(ctx)
 		if err != nil {
 			log.Fatal(err)
 		}
 	},
 }
 
+// loadConfig reads the config file and finds the API key, exiting if there
+// is none.
+func loadConfig() (*config.Config, string) {
+	conf, err := config.LoadConfig()
+	if err != nil && err != config.NoConfigErr {
+		log.Fatalf("Read config file err: %s", err)
+	}
+
+	apiKey := conf.AnthropicApiKey
+
+	if apiKey == "" {
+		apiKey = os.Getenv("CLAUDE_API_KEY")
+		if apiKey == "" {
+			log.Fatalf("No API key found in config file %s or environment variable CLAUDE_API_KEY", config.ConfigFilePath())
+		}
+	}
+
+	return conf, apiKey
+}
+
 func Execute() error {
 	models := claude.CurrentModels()
 	rootCmd.Flags().StringVar(&modelFlag, "model", "", fmt.Sprintf("model name (%s)", strings.Join(models, ",")))
 	rootCmd.Flags().StringVar(&debugLog, "debug-log", "", "Path to write debug log")
 	rootCmd.Flags().StringVar(&systemPrompt, "system-prompt", "", "Override code-buddy's default system prompt with your own")
+	rootCmd.Flags().StringVar(&promptFlag, "prompt", "", "Name of a custom prompt template from the config file or prompts directory")
 	rootCmd.Flags().StringArrayVar(&files, "file", nil, "Include file(s) in context")
 	rootCmd.Flags().BoolVar(&listModels, "list-models", false, "List known models")
 	rootCmd.Flags().BoolVar(&punFlag, "pun", false, "Pun mode")
+	rootCmd.Flags().BoolVar(&autoCommit, "auto-commit", false, "Commit the assistant's approved edits after each request")
+	rootCmd.Flags().BoolVar(&noTools, "no-tools", false, "Don't let the model use tools; useful with --file")
+
+	commitCmd.Flags().StringVar(&commitModelFlag, "model", "", "model name")
+	commitCmd.Flags().BoolVar(&commitNoEdit, "no-edit", false, "Commit with the generated message without opening an editor")
+	commitCmd.Flags().BoolVar(&commitPrint, "print", false, "Print the generated message without committing")
+	rootCmd.AddCommand(commitCmd)
+
+	reviewCmd.Flags().StringVar(&reviewModelFlag, "model", "", "model name")
+	reviewCmd.Flags().StringVar(&reviewFormat, "format", "text", "Output format: text, json or github")
+	reviewCmd.Flags().StringVar(&reviewFailOn, "fail-on", "", "Exit with status 1 if there are comments of this severity or worse (error, warning, suggestion, nit)")
+	reviewCmd.Flags().IntVar(&reviewMaxTurns, "max-turns", 0, "Maximum number of model requests")
+	reviewCmd.Flags().StringVar(&debugLog, "debug-log", "", "Path to write debug log")
+	rootCmd.AddCommand(reviewCmd)
+
+	mcpServeCmd.Flags().StringVar(&mcpServeDir, "dir", "", "Directory to serve (default the current directory)")
+	rootCmd.AddCommand(mcpServeCmd)
 
 	return rootCmd.Execute()
 }
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/psanford/claude"
+	"github.com/psanford/claude/anthropic"
+	"github.com/psanford/code-buddy/commitmsg"
+	"github.com/psanford/code-buddy/interactive"
+	"github.com/spf13/cobra"
+)
+
+var (
+	commitModelFlag string
+	commitNoEdit    bool
+	commitPrint     bool
+)
+
+var commitCmd = &cobra.Command{
+	Use:   "commit",
+	Short: "Draft a commit message for the staged changes and commit them",
+	Long: `Draft a commit message for the staged changes (git diff --staged) in the
+style of the repository's recent commits. The message is opened in your
+editor by git commit before committing, unless --no-edit or --print is given.`,
+	Args: cobra.NoArgs,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+
+		diff, err := exec.Command("git", "diff", "--staged").Output()
+		if err != nil {
+			log.Fatalf("git diff --staged err: %s", err)
+		}
+		if len(diff) == 0 {
+			log.Fatalf("No staged changes; stage them with git add first")
+		}
+
+		conf, apiKey := loadConfig()
+
+		model := commitModelFlag
+		if model == "" {
+			model = conf.Model
+		}
+		if model == "" {
+			model = claude.Claude3Dot7SonnetLatest
+		}
+
+		gen := commitmsg.Generator{
+			Client: anthropic.NewClient(apiKey),
+			Model:  interactive.FullModelName(model),
+			Out:    os.Stderr,
+		}
+		if commitPrint {
+			gen.Out = nil
+		}
+
+		msg, err := gen.Message(ctx, commitmsg.Request{
+			Diff:     string(diff),
+			Examples: commitmsg.RecentMessages(10),
+		})
+		if err != nil {
+			log.Fatalf("Generate commit message err: %s", err)
+		}
+
+		if commitPrint {
+			fmt.Print(msg)
+			return
+		}
+		fmt.Fprintln(os.Stderr)
+
+		f, err := os.CreateTemp("", "code-buddy-commit-*.txt")
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer os.Remove(f.Name())
+		if _, err := f.WriteString(msg); err != nil {
+			log.Fatal(err)
+		}
+		f.Close()
+
+		gitArgs := []string{"commit", "-F", f.Name()}
+		if !commitNoEdit {
+			// git opens its configured editor ($GIT_EDITOR, core.editor,
+			// $VISUAL or $EDITOR) with the message filled in.
+			gitArgs = append(gitArgs, "--edit")
+		}
+		gitCmd := exec.Command("git", gitArgs...)
+		gitCmd.Stdin = os.Stdin
+		gitCmd.Stdout = os.Stdout
+		gitCmd.Stderr = os.Stderr
+		if err := gitCmd.Run(); err != nil {
+			os.Exit(1)
+		}
+	},
+}
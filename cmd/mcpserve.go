@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/psanford/code-buddy/config"
+	"github.com/psanford/code-buddy/interactive"
+	"github.com/spf13/cobra"
+)
+
+var mcpServeDir string
+
+var mcpServeCmd = &cobra.Command{
+	Use:   "mcp-serve",
+	Short: "Run an MCP server exposing code-buddy's file tools",
+	Long: `Run a Model Context Protocol server over stdin and stdout, so other MCP
+clients can use code-buddy's cat, list_files, rg and edit tools. The tools
+can only access files in the served directory (default the current
+directory). The edit tools take a preview parameter that returns the change
+without applying it, and run the configured post-edit checks.`,
+	Args: cobra.NoArgs,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+
+		// stdout is the protocol stream; anything else goes to stderr
+		log.SetOutput(os.Stderr)
+
+		if mcpServeDir != "" {
+			if err := os.Chdir(mcpServeDir); err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		conf, err := config.LoadConfig()
+		if err != nil && err != config.NoConfigErr {
+			log.Fatalf("Read config file err: %s", err)
+		}
+
+		r := interactive.Runner{
+			CatMaxLines: conf.CatMaxLines,
+			PostEditChecks: interactive.PostEditChecks{
+				Gofmt:     conf.PostEditGofmt,
+				Goimports: conf.PostEditGoimports,
+				Vet:       conf.PostEditVet,
+				Build:     conf.PostEditBuild,
+			},
+		}
+
+		server, err := r.MCPServer()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := server.Serve(ctx, os.Stdin, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
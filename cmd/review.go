@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/psanford/claude"
+	"github.com/psanford/code-buddy/interactive"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reviewModelFlag string
+	reviewFormat    string
+	reviewFailOn    string
+	reviewMaxTurns  int
+)
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+var reviewCmd = &cobra.Command{
+	Use:   "review [<range>]",
+	Short: "Review a diff or branch",
+	Long: `Ask the model to review a change. With no argument the uncommitted changes,
+including untracked files, are reviewed. Otherwise the argument is passed to
+git diff: main..feature compares the two revisions, main...feature compares
+feature with the point where it diverged from main (the changes a pull
+request would merge), and a single revision is compared with the working
+tree.
+
+The model can read the rest of the project with read-only tools but is never
+offered tools that modify files. The tools run without asking. The Go symbol
+tools run "go list", which may download the toolchain named in go.mod and run
+cgo and pkg-config, so review untrusted changes in an isolated environment.
+
+Formats:
+  text    file:line: severity: message, for terminals and editors
+  json    {"range", "summary", "comments": [{"file", "line", "severity", "message"}]}
+  github  GitHub Actions annotations`,
+	Args: cobra.MaximumNArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+
+		if !slices.Contains([]string{"text", "json", "github"}, reviewFormat) {
+			log.Fatalf("Unknown format %q", reviewFormat)
+		}
+		if reviewFailOn != "" && !slices.Contains(interactive.ReviewSeverities, reviewFailOn) {
+			log.Fatalf("--fail-on must be one of %s", strings.Join(interactive.ReviewSeverities, ", "))
+		}
+
+		conf, apiKey := loadConfig()
+
+		model := reviewModelFlag
+		if model == "" {
+			model = conf.Model
+		}
+		if model == "" {
+			model = claude.Claude3Dot7SonnetLatest
+		}
+
+		r := interactive.Runner{
+			APIKey:      apiKey,
+			Model:       model,
+			CatMaxLines: conf.CatMaxLines,
+		}
+		if debugLog != "" {
+			f, err := os.OpenFile(debugLog, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer f.Close()
+			r.DebugLogger = slog.New(slog.NewJSONHandler(f, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		}
+
+		var rangeArg string
+		if len(args) > 0 {
+			rangeArg = args[0]
+		}
+
+		review, err := r.Review(ctx, interactive.ReviewOptions{
+			Range:    rangeArg,
+			MaxTurns: reviewMaxTurns,
+			Log:      os.Stderr,
+		})
+		if err != nil {
+			log.Fatalf("Review err: %s", err)
+		}
+
+		switch reviewFormat {
+		case "json":
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(review); err != nil {
+				log.Fatal(err)
+			}
+		case "github":
+			review.WriteGitHub(os.Stdout)
+		default:
+			review.WriteText(os.Stdout, isTerminal(os.Stdout))
+		}
+
+		if reviewFailOn != "" && review.HasSeverity(reviewFailOn) {
+			fmt.Fprintf(os.Stderr, "review has %s or more severe comments\n", reviewFailOn)
+			os.Exit(1)
+		}
+	},
+}
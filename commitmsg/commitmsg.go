@@ -0,0 +1,273 @@
+// Package commitmsg asks the model to write a git commit message for a diff.
+package commitmsg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/psanford/claude"
+	"github.com/psanford/claude/clientiface"
+	"github.com/psanford/code-buddy/accumulator"
+)
+
+const (
+	// maxDiffBytes limits how much of the diff is sent to the model.
+	// Larger diffs are sent per file, with big files summarized first.
+	maxDiffBytes = 60000
+	// files with a diff larger than this are summarized
+	maxFileDiffBytes = 12000
+	// limit on summary requests for a single message
+	maxSummarizedFiles = 20
+)
+
+const systemPrompt = `You write git commit messages. Reply with only the commit message: a summary line of at most 72 characters in the imperative mood, then a blank line and a short body explaining what changed and why when the change is not trivial. Do not wrap the message in quotes or a code block.
+
+When examples of recent commit messages from the repository are provided, match their conventions: prefixes such as Conventional Commits types and scopes ("fix(parser): ...") or package names ("net/http: ..."), capitalization, tense, and how long the body usually is.`
+
+const summarySystemPrompt = `You summarize changes to a single file for someone writing a commit message. Reply with one to three sentences describing what changed in the file, without preamble.`
+
+type Generator struct {
+	Client      clientiface.Client
+	Model       string
+	DebugLogger *slog.Logger
+	// Out, if set, receives the final message as it is streamed.
+	Out io.Writer
+}
+
+type Request struct {
+	Diff string
+	// Context is optional extra information for the model, such as the
+	// user request that led to the change.
+	Context string
+	// Examples are recent commit messages from the repository whose style
+	// the message should follow.
+	Examples []string
+}
+
+// Message returns a commit message for req.Diff.
+func (g *Generator) Message(ctx context.Context, req Request) (string, error) {
+	if strings.TrimSpace(req.Diff) == "" {
+		return "", fmt.Errorf("empty diff")
+	}
+
+	diff, err := g.fitDiff(ctx, req.Diff)
+	if err != nil {
+		return "", err
+	}
+
+	var prompt strings.Builder
+	if len(req.Examples) > 0 {
+		prompt.WriteString("<recent_commit_messages>\n")
+		for _, ex := range req.Examples {
+			fmt.Fprintf(&prompt, "<message>\n%s\n</message>\n", strings.TrimSpace(ex))
+		}
+		prompt.WriteString("</recent_commit_messages>\n\n")
+	}
+	if req.Context != "" {
+		fmt.Fprintf(&prompt, "<context>\n%s\n</context>\n\n", strings.TrimSpace(req.Context))
+	}
+	fmt.Fprintf(&prompt, "<diff>\n%s\n</diff>\n\nWrite the commit message for this diff.", diff)
+
+	msg, err := g.complete(ctx, systemPrompt, prompt.String(), g.Out)
+	if err != nil {
+		return "", err
+	}
+	return cleanMessage(msg), nil
+}
+
+type fileDiff struct {
+	name           string
+	text           string
+	added, removed int
+}
+
+// splitDiff splits a git diff into per-file sections.
+func splitDiff(diff string) []*fileDiff {
+	var (
+		files []*fileDiff
+		cur   *fileDiff
+		buf   strings.Builder
+	)
+	flush := func() {
+		if cur != nil {
+			cur.text = buf.String()
+			files = append(files, cur)
+		}
+		buf.Reset()
+	}
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			flush()
+			cur = &fileDiff{name: diffFileName(line)}
+		} else if cur == nil {
+			cur = &fileDiff{}
+		}
+		switch {
+		case strings.HasPrefix(line, "+++ "), strings.HasPrefix(line, "--- "):
+		case strings.HasPrefix(line, "+"):
+			cur.added++
+		case strings.HasPrefix(line, "-"):
+			cur.removed++
+		}
+		buf.WriteString(line)
+	}
+	flush()
+	return files
+}
+
+// diffFileName extracts the new file name from a "diff --git a/x b/y" line.
+func diffFileName(header string) string {
+	header = strings.TrimSpace(strings.TrimPrefix(header, "diff --git "))
+	if idx := strings.LastIndex(header, " b/"); idx >= 0 {
+		return header[idx+3:]
+	}
+	return header
+}
+
+// fitDiff returns the diff unchanged if it is small enough to send whole.
+// Otherwise files are included until the budget is used up, files with
+// very large diffs are replaced by a model written summary, and anything
+// left over is listed with its line counts only.
+func (g *Generator) fitDiff(ctx context.Context, diff string) (string, error) {
+	if len(diff) <= maxDiffBytes {
+		return diff, nil
+	}
+
+	var (
+		buf        strings.Builder
+		remaining  = maxDiffBytes
+		summarized int
+		omitted    []string
+	)
+	for _, f := range splitDiff(diff) {
+		if len(f.text) <= maxFileDiffBytes && len(f.text) <= remaining {
+			buf.WriteString(f.text)
+			remaining -= len(f.text)
+			continue
+		}
+		stat := fmt.Sprintf("%s (+%d -%d lines)", f.name, f.added, f.removed)
+		if summarized < maxSummarizedFiles {
+			summary, err := g.summarize(ctx, f)
+			if err != nil {
+				return "", err
+			}
+			summarized++
+			entry := fmt.Sprintf("[diff of %s too large to include; summary: %s]\n", stat, strings.TrimSpace(summary))
+			buf.WriteString(entry)
+			remaining -= len(entry)
+			continue
+		}
+		omitted = append(omitted, stat)
+	}
+	if len(omitted) > 0 {
+		fmt.Fprintf(&buf, "[%d more changed files not shown: %s]\n", len(omitted), strings.Join(omitted, ", "))
+	}
+	return buf.String(), nil
+}
+
+func (g *Generator) summarize(ctx context.Context, f *fileDiff) (string, error) {
+	text := f.text
+	if len(text) > maxDiffBytes {
+		text = text[:maxDiffBytes] + "\n[diff truncated]\n"
+	}
+	prompt := fmt.Sprintf("<diff>\n%s\n</diff>\n\nSummarize the changes to %s.", text, f.name)
+	return g.complete(ctx, summarySystemPrompt, prompt, nil)
+}
+
+func (g *Generator) complete(ctx context.Context, system, prompt string, out io.Writer) (string, error) {
+	acc := accumulator.New(g.Client, accumulator.WithDebugLogger(g.DebugLogger))
+	req := &claude.MessageRequest{
+		Model:     g.Model,
+		System:    system,
+		MaxTokens: 1024,
+		Messages: []claude.MessageTurn{
+			{
+				Role:    "user",
+				Content: []claude.TurnContent{claude.TextContent(prompt)},
+			},
+		},
+	}
+
+	var opts []accumulator.CompleteOption
+	done := make(chan struct{})
+	if out != nil {
+		cbCh := make(chan accumulator.ContentBlock)
+		opts = append(opts, accumulator.WithContentBlockDeltaChan(cbCh))
+		go func() {
+			defer close(done)
+			for cb := range cbCh {
+				io.WriteString(out, cb.Text)
+			}
+		}()
+	} else {
+		close(done)
+	}
+
+	resp, err := acc.Complete(ctx, req, opts...)
+	<-done
+	if err != nil {
+		return "", err
+	}
+
+	var msg strings.Builder
+	for _, content := range resp.Content {
+		if blk, ok := content.(*accumulator.ContentBlock); ok && blk.Type() == "text" {
+			msg.WriteString(blk.Text)
+		}
+	}
+	return msg.String(), nil
+}
+
+// RecentMessages returns the messages of the last n commits on HEAD,
+// without trailers, for use as Request.Examples. It returns nil if there
+// are no commits yet.
+func RecentMessages(n int) []string {
+	out, err := exec.Command("git", "log", "-n", strconv.Itoa(n), "--no-merges", "--format=%B%x00").Output()
+	if err != nil {
+		return nil
+	}
+	var msgs []string
+	for _, m := range strings.Split(string(out), "\x00") {
+		m = strings.TrimSpace(stripTrailers(m))
+		if m != "" {
+			msgs = append(msgs, m)
+		}
+	}
+	return msgs
+}
+
+// stripTrailers removes a final paragraph made up of "Key: value" lines,
+// such as Signed-off-by, so the model doesn't copy them.
+func stripTrailers(msg string) string {
+	msg = strings.TrimSpace(msg)
+	idx := strings.LastIndex(msg, "\n\n")
+	if idx < 0 {
+		return msg
+	}
+	for _, line := range strings.Split(msg[idx+2:], "\n") {
+		key, _, ok := strings.Cut(line, ": ")
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return msg
+		}
+	}
+	return msg[:idx]
+}
+
+// cleanMessage strips code fences and surrounding whitespace that models
+// sometimes add despite being asked not to.
+func cleanMessage(msg string) string {
+	msg = strings.TrimSpace(msg)
+	if strings.HasPrefix(msg, "```") {
+		msg = strings.TrimPrefix(msg, "```")
+		if idx := strings.IndexByte(msg, '\n'); idx >= 0 {
+			msg = msg[idx+1:]
+		}
+		msg = strings.TrimSuffix(strings.TrimSpace(msg), "```")
+	}
+	return strings.TrimSpace(msg) + "\n"
+}
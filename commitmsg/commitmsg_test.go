@@ -0,0 +1,101 @@
+package commitmsg
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/psanford/claude"
+	"github.com/psanford/claude/clientiface"
+)
+
+type errClient struct{ err error }
+
+func (c errClient) Message(ctx context.Context, req *claude.MessageRequest, options ...clientiface.Option) (claude.MessageResponse, error) {
+	return nil, c.err
+}
+
+func TestMessageClientError(t *testing.T) {
+	apiErr := errors.New("invalid x-api-key")
+	g := &Generator{Client: errClient{apiErr}, Out: &strings.Builder{}}
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := g.Message(context.Background(), Request{Diff: "diff --git a/a b/a\n+x\n"})
+		errCh <- err
+	}()
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, apiErr) {
+			t.Errorf("err = %v, want %v", err, apiErr)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Message hung after the request failed")
+	}
+}
+
+func TestCleanMessage(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Fix parser\n\nDetails here.", "Fix parser\n\nDetails here.\n"},
+		{"  Fix parser  \n", "Fix parser\n"},
+		{"```\nFix parser\n\nBody\n```", "Fix parser\n\nBody\n"},
+		{"```text\nFix parser\n```\n", "Fix parser\n"},
+	}
+	for _, tc := range tests {
+		if got := cleanMessage(tc.in); got != tc.want {
+			t.Errorf("cleanMessage(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestSplitDiff(t *testing.T) {
+	diff := `diff --git a/a.go b/a.go
+index 1..2 100644
+--- a/a.go
++++ b/a.go
+@@ -1,2 +1,2 @@
+-old
++new
++more
+diff --git a/dir/b b.txt b/dir/b b.txt
+new file mode 100644
+--- /dev/null
++++ b/dir/b b.txt
+@@ -0,0 +1 @@
++b
+`
+	files := splitDiff(diff)
+	if len(files) != 2 {
+		t.Fatalf("got %d files", len(files))
+	}
+	if files[0].name != "a.go" || files[0].added != 2 || files[0].removed != 1 {
+		t.Errorf("file 0: %+v", files[0])
+	}
+	if files[1].name != "dir/b b.txt" || files[1].added != 1 || files[1].removed != 0 {
+		t.Errorf("file 1: %+v", files[1])
+	}
+	if files[0].text+files[1].text != diff {
+		t.Errorf("file sections don't add up to the diff")
+	}
+}
+
+func TestStripTrailers(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Fix bug\n\nBody text.\n\nSigned-off-by: A <a@example.com>\nCode-Buddy-Session: 1\n", "Fix bug\n\nBody text."},
+		{"Fix bug\n\nBody text: with colon in a sentence", "Fix bug\n\nBody text: with colon in a sentence"},
+		{"Fix bug", "Fix bug"},
+	}
+	for _, tc := range tests {
+		if got := stripTrailers(tc.in); got != tc.want {
+			t.Errorf("stripTrailers(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
@@ -11,12 +11,85 @@ import (
 type Config struct {
 	AnthropicApiKey string         `toml:"anthropic_api_key"`
 	CustomPrompts   []CustomPrompt `toml:"custom_prompt"`
-	Model           string         `toml:"model"` // default model to use
+	Commands        []SlashCommand `toml:"command"`
+	Hooks           []Hook         `toml:"hook"`
+	MCPServers      []MCPServer    `toml:"mcp_server"`
+	Model           string         `toml:"model"`             // default model to use
+	DefaultPrompt   string         `toml:"default_prompt"`    // custom prompt used at startup
+	CatMaxLines     int            `toml:"cat_max_lines"`     // max lines returned by a single cat call
+	TreeDepth       int            `toml:"tree_depth"`        // directory levels shown in the system prompt
+	TreeTokenBudget int            `toml:"tree_token_budget"` // approximate token limit for the system prompt tree
+
+	RepoMapTokenBudget int  `toml:"repo_map_token_budget"` // approximate token limit for the go repo map
+	DisableRepoMap     bool `toml:"disable_repo_map"`
+
+	// git state included in the system prompt
+	DisableGitContext bool `toml:"disable_git_context"`
+	GitStatusMaxLines int  `toml:"git_status_max_lines"` // porcelain status lines shown
+	GitRecentCommits  int  `toml:"git_recent_commits"`   // number of recent commit subjects shown
+
+	// commit approved edits after each request, optionally on a
+	// code-buddy/<session> branch (which requires no other uncommitted
+	// changes to tracked files)
+	AutoCommit       bool `toml:"auto_commit"`
+	AutoCommitBranch bool `toml:"auto_commit_branch"`
+
+	// don't offer the model any tools, e.g. when working only from
+	// attached files
+	DisableTools bool `toml:"disable_tools"`
+
+	// limits for sub-agents started with the delegate tool
+	DelegateMaxTurns  int `toml:"delegate_max_turns"`  // model requests per delegated task
+	DelegateMaxTokens int `toml:"delegate_max_tokens"` // input plus output tokens per delegated task
+
+	// optional checks run after a tool modifies a .go file; all are off
+	// by default
+	PostEditGofmt     bool `toml:"post_edit_gofmt"`
+	PostEditGoimports bool `toml:"post_edit_goimports"`
+	PostEditVet       bool `toml:"post_edit_vet"`
+	PostEditBuild     bool `toml:"post_edit_build"`
 }
 
 type CustomPrompt struct {
 	Name   string `toml:"name"`
 	Prompt string `toml:"prompt"`
+	// File is a template file to read the prompt from instead of Prompt.
+	// Relative paths are resolved against the config directory.
+	File string `toml:"file"`
+}
+
+// SlashCommand is a user-defined /<name> command that sends Prompt, with
+// $ARGUMENTS replaced by the text after the command.
+type SlashCommand struct {
+	Name        string `toml:"name"`
+	Description string `toml:"description"`
+	Prompt      string `toml:"prompt"`
+	// AllowedTools restricts the tools the model may use while handling
+	// the command. Empty allows all tools.
+	AllowedTools []string `toml:"allowed_tools"`
+}
+
+// Hook is an external command run at a point in the session's lifecycle.
+// It receives the event as JSON on stdin.
+type Hook struct {
+	// Event is pre-tool-use, post-tool-use, user-prompt-submit or stop.
+	Event string `toml:"event"`
+	// Matcher is a regular expression matched against the whole tool
+	// name for tool events. Empty matches every tool.
+	Matcher string `toml:"matcher"`
+	Command string `toml:"command"`
+	Timeout int    `toml:"timeout"` // seconds
+}
+
+// MCPServer is a Model Context Protocol server whose tools are offered to
+// the model. It is started as a subprocess and spoken to over stdio.
+type MCPServer struct {
+	// Name prefixes the server's tool names: mcp__<name>__<tool>.
+	Name    string            `toml:"name"`
+	Command string            `toml:"command"`
+	Args    []string          `toml:"args"`
+	Env     map[string]string `toml:"env"`
+	Timeout int               `toml:"timeout"` // seconds per tool call
 }
 
 var NoConfigErr = errors.New("no config")
@@ -42,6 +115,26 @@ func LoadConfig() (*Config, error) {
 	return &conf, nil
 }
 
+// PromptsDir is the directory custom prompt templates are loaded from.
+// Each file is a prompt named after the file without its extension.
+func PromptsDir() string {
+	confFile := ConfigFilePath()
+	if confFile == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(confFile), "prompts")
+}
+
+// CommandsDir is the directory user-wide slash commands are loaded from.
+// Each markdown file is a command named after the file.
+func CommandsDir() string {
+	confFile := ConfigFilePath()
+	if confFile == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(confFile), "commands")
+}
+
 func ConfigFilePath() string {
 	userConfDir, _ := os.UserConfigDir()
 	if userConfDir == "" {
@@ -0,0 +1,176 @@
+package fswalk
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ignoreRule is a single pattern from a .gitignore style file.
+type ignoreRule struct {
+	// base is the slash separated directory containing the ignore file,
+	// relative to the walk root. Rules only apply to paths below base.
+	base string
+	// prefix is the path of the walk root relative to the directory
+	// containing the ignore file, for files in the root's ancestors.
+	prefix  string
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+func (r *ignoreRule) match(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.base != "" {
+		if !strings.HasPrefix(rel, r.base+"/") {
+			return false
+		}
+		rel = rel[len(r.base)+1:]
+	}
+	if r.prefix != "" {
+		rel = r.prefix + "/" + rel
+	}
+	return r.re.MatchString(rel)
+}
+
+// ignoreList is an ordered set of rules. Later rules take precedence over
+// earlier ones, matching git's behavior.
+type ignoreList []*ignoreRule
+
+func (l ignoreList) ignored(rel string, isDir bool) bool {
+	for i := len(l) - 1; i >= 0; i-- {
+		if l[i].match(rel, isDir) {
+			return !l[i].negate
+		}
+	}
+	return false
+}
+
+// loadIgnoreFile parses a .gitignore style file. A missing file is not an error.
+func loadIgnoreFile(path, base string) (ignoreList, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return parseIgnore(b, base), nil
+}
+
+func parseIgnore(content []byte, base string) ignoreList {
+	var rules ignoreList
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := ignoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		// Patterns containing a slash are anchored to the ignore file's
+		// directory; others match a name at any depth.
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+
+		expr := globToRegexp(line)
+		if anchored {
+			expr = "^" + expr + "$"
+		} else {
+			expr = "^(?:.*/)?" + expr + "$"
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			continue
+		}
+		rule.re = re
+		rules = append(rules, &rule)
+	}
+	return rules
+}
+
+// globToRegexp converts a gitignore glob into an (unanchored) regular
+// expression. Brace alternatives like "*.{js,ts}" are also supported, as
+// they are in rg's --glob flag.
+func globToRegexp(glob string) string {
+	var (
+		buf        strings.Builder
+		braceDepth int
+	)
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '{':
+			braceDepth++
+			buf.WriteString("(?:")
+		case '}':
+			if braceDepth == 0 {
+				buf.WriteString(`\}`)
+				continue
+			}
+			braceDepth--
+			buf.WriteString(")")
+		case ',':
+			if braceDepth > 0 {
+				buf.WriteString("|")
+			} else {
+				buf.WriteString(",")
+			}
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					// "**/" matches zero or more directories
+					i++
+					buf.WriteString("(?:.*/)?")
+				} else {
+					buf.WriteString(".*")
+				}
+			} else {
+				buf.WriteString("[^/]*")
+			}
+		case '?':
+			buf.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				buf.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			buf.WriteString("[" + class + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				buf.WriteString(regexp.QuoteMeta(string(glob[i])))
+			}
+		default:
+			buf.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	for ; braceDepth > 0; braceDepth-- {
+		buf.WriteString(")")
+	}
+	return buf.String()
+}
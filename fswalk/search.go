@@ -0,0 +1,196 @@
+package fswalk
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Match is a single line matching a search pattern, or a line of context
+// surrounding one.
+type Match struct {
+	Path    string // as it should be displayed, e.g. "./sub/file.go"
+	Line    int    // 1-indexed
+	Text    string
+	Context bool // true for context lines, false for matching lines
+}
+
+type SearchOptions struct {
+	// Context is the number of lines to include before and after each match.
+	Context int
+	// Globs include or, when prefixed with "!", exclude files, in the
+	// same format as rg's --glob flag.
+	Globs []string
+	// Types restrict the search to known file types, like rg's --type flag.
+	Types []string
+}
+
+// fileTypes is a subset of ripgrep's built-in file type definitions.
+var fileTypes = map[string][]string{
+	"c":          {"*.c", "*.h"},
+	"cpp":        {"*.cc", "*.cpp", "*.cxx", "*.hh", "*.hpp", "*.hxx", "*.h"},
+	"css":        {"*.css", "*.scss"},
+	"go":         {"*.go"},
+	"html":       {"*.html", "*.htm"},
+	"java":       {"*.java"},
+	"js":         {"*.js", "*.jsx", "*.mjs", "*.cjs"},
+	"json":       {"*.json"},
+	"markdown":   {"*.md", "*.markdown"},
+	"md":         {"*.md", "*.markdown"},
+	"proto":      {"*.proto"},
+	"py":         {"*.py", "*.pyi"},
+	"ruby":       {"*.rb"},
+	"rust":       {"*.rs"},
+	"sh":         {"*.sh", "*.bash", "*.zsh"},
+	"sql":        {"*.sql"},
+	"toml":       {"*.toml"},
+	"ts":         {"*.ts", "*.tsx", "*.mts", "*.cts"},
+	"typescript": {"*.ts", "*.tsx", "*.mts", "*.cts"},
+	"yaml":       {"*.yaml", "*.yml"},
+}
+
+// fileFilter decides which walked files are searched based on
+// SearchOptions globs and types.
+type fileFilter struct {
+	includes ignoreList
+	excludes ignoreList
+	types    ignoreList
+}
+
+func newFileFilter(opts SearchOptions) (*fileFilter, error) {
+	var f fileFilter
+	for _, g := range opts.Globs {
+		if strings.HasPrefix(g, "!") {
+			f.excludes = append(f.excludes, parseIgnore([]byte(g[1:]), "")...)
+		} else {
+			f.includes = append(f.includes, parseIgnore([]byte(g), "")...)
+		}
+	}
+	for _, t := range opts.Types {
+		globs, ok := fileTypes[t]
+		if !ok {
+			return nil, fmt.Errorf("unrecognized file type %q (known types: %s)", t, strings.Join(knownTypes(), ", "))
+		}
+		f.types = append(f.types, parseIgnore([]byte(strings.Join(globs, "\n")), "")...)
+	}
+	return &f, nil
+}
+
+func (f *fileFilter) include(rel string) bool {
+	if len(f.includes) > 0 && !f.includes.ignored(rel, false) {
+		return false
+	}
+	if len(f.types) > 0 && !f.types.ignored(rel, false) {
+		return false
+	}
+	return !f.excludes.ignored(rel, false)
+}
+
+func knownTypes() []string {
+	types := make([]string, 0, len(fileTypes))
+	for t := range fileTypes {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Search finds all lines matching re below target, which may be a file or
+// a directory. When target is a directory, paths are displayed the same
+// way ripgrep does: prefixed with target unless target is empty.
+func Search(target string, re *regexp.Regexp, opts SearchOptions) ([]Match, error) {
+	var matches []Match
+
+	if target != "" {
+		info, err := os.Stat(target)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return searchFile(target, target, re, opts.Context, matches)
+		}
+	}
+
+	filter, err := newFileFilter(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	root := target
+	if root == "" {
+		root = "."
+	}
+
+	err = Walk(root, func(rel string, d fs.DirEntry) error {
+		if d.IsDir() || !filter.include(rel) {
+			return nil
+		}
+		display := rel
+		if target != "" {
+			display = strings.TrimSuffix(target, "/") + "/" + rel
+		}
+
+		var err error
+		matches, err = searchFile(filepath.Join(root, filepath.FromSlash(rel)), display, re, opts.Context, matches)
+		return err
+	})
+
+	return matches, err
+}
+
+func searchFile(filename, display string, re *regexp.Regexp, context int, matches []Match) ([]Match, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return matches, err
+	}
+	if isBinary(content) {
+		return matches, nil
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(content)+1)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return matches, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	// next is the index of the first line not yet emitted, so overlapping
+	// context windows don't produce duplicate lines.
+	next := 0
+	for i, line := range lines {
+		if !re.MatchString(line) {
+			continue
+		}
+		for j := max(i-context, next); j < i; j++ {
+			matches = append(matches, Match{Path: display, Line: j + 1, Text: lines[j], Context: true})
+		}
+		matches = append(matches, Match{Path: display, Line: i + 1, Text: line})
+		next = i + 1
+
+		// trailing context stops at the next match, which will be emitted
+		// by the outer loop
+		for j := i + 1; j <= i+context && j < len(lines) && !re.MatchString(lines[j]); j++ {
+			matches = append(matches, Match{Path: display, Line: j + 1, Text: lines[j], Context: true})
+			next = j + 1
+		}
+	}
+	return matches, nil
+}
+
+// isBinary reports whether content looks like a binary file using the
+// same heuristic as git and ripgrep: a NUL byte near the start.
+func isBinary(content []byte) bool {
+	if len(content) > 8000 {
+		content = content[:8000]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
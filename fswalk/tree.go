@@ -0,0 +1,168 @@
+package fswalk
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/psanford/code-buddy/internal/textutil"
+)
+
+type TreeOptions struct {
+	// MaxDepth is the number of directory levels to expand. Deeper
+	// directories are shown with a summary only. 0 means unlimited.
+	MaxDepth int
+	// MaxEntries is the number of entries shown per directory before the
+	// rest are collapsed into a summary line. 0 means unlimited.
+	MaxEntries int
+	// TokenBudget is an approximate limit on the size of the rendered tree,
+	// estimated at 4 bytes per token. The depth is reduced until the tree
+	// fits. 0 means unlimited.
+	TokenBudget int
+}
+
+type treeNode struct {
+	name     string
+	isDir    bool
+	size     int64 // total size of all files below this node
+	files    int   // total number of files below this node
+	children []*treeNode
+}
+
+// Tree renders a directory tree of root, skipping hidden and ignored files.
+// Each directory is annotated with the number of files it contains and
+// their total size.
+func Tree(root string, opts TreeOptions) (string, error) {
+	top := &treeNode{name: root, isDir: true}
+	dirs := map[string]*treeNode{"": top}
+
+	err := Walk(root, func(rel string, d fs.DirEntry) error {
+		parent := dirs[path.Dir(rel)]
+		if path.Dir(rel) == "." {
+			parent = top
+		}
+
+		node := &treeNode{name: d.Name(), isDir: d.IsDir()}
+		if d.IsDir() {
+			dirs[rel] = node
+		} else {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			node.size = info.Size()
+			node.files = 1
+		}
+		parent.children = append(parent.children, node)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sumTree(top)
+
+	maxDepth := opts.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = treeDepth(top)
+	}
+
+	for depth := maxDepth; ; depth-- {
+		var buf strings.Builder
+		fmt.Fprintf(&buf, "%s/ (%s)\n", strings.TrimSuffix(root, "/"), summarize(top))
+		renderTree(&buf, top, "", 1, depth, opts.MaxEntries)
+
+		out := buf.String()
+		budget := opts.TokenBudget * 4
+		if budget <= 0 || len(out) <= budget {
+			return out, nil
+		}
+		if depth <= 1 {
+			// Even a single level is too big; cut it off at a line boundary.
+			cut := strings.LastIndexByte(out[:budget], '\n')
+			if cut < 0 {
+				cut = 0
+			}
+			return out[:cut+1] + "… (truncated to fit token budget)\n", nil
+		}
+	}
+}
+
+func sumTree(n *treeNode) {
+	if !n.isDir {
+		return
+	}
+	n.size, n.files = 0, 0
+	for _, c := range n.children {
+		sumTree(c)
+		n.size += c.size
+		n.files += c.files
+	}
+	// directories first, then files, each alphabetically
+	sort.SliceStable(n.children, func(i, j int) bool {
+		if n.children[i].isDir != n.children[j].isDir {
+			return n.children[i].isDir
+		}
+		return n.children[i].name < n.children[j].name
+	})
+}
+
+func treeDepth(n *treeNode) int {
+	depth := 0
+	for _, c := range n.children {
+		if c.isDir {
+			depth = max(depth, treeDepth(c))
+		}
+	}
+	return depth + 1
+}
+
+func renderTree(buf *strings.Builder, n *treeNode, indent string, depth, maxDepth, maxEntries int) {
+	children := n.children
+	var hidden []*treeNode
+	if maxEntries > 0 && len(children) > maxEntries {
+		children, hidden = children[:maxEntries], children[maxEntries:]
+	}
+
+	for i, c := range children {
+		branch, childIndent := "├── ", indent+"│   "
+		if i == len(children)-1 && len(hidden) == 0 {
+			branch, childIndent = "└── ", indent+"    "
+		}
+
+		if !c.isDir {
+			fmt.Fprintf(buf, "%s%s%s (%s)\n", indent, branch, c.name, textutil.FormatSize(c.size))
+			continue
+		}
+
+		fmt.Fprintf(buf, "%s%s%s/ (%s)\n", indent, branch, c.name, summarize(c))
+		if depth < maxDepth {
+			renderTree(buf, c, childIndent, depth+1, maxDepth, maxEntries)
+		}
+	}
+
+	if len(hidden) > 0 {
+		var (
+			dirs, files int
+			size        int64
+		)
+		for _, c := range hidden {
+			if c.isDir {
+				dirs++
+			}
+			files += c.files
+			size += c.size
+		}
+		desc := fmt.Sprintf("%d more %s", len(hidden), textutil.Plural(len(hidden), "entry", "entries"))
+		if dirs > 0 {
+			desc += fmt.Sprintf(" (%d %s)", dirs, textutil.Plural(dirs, "directory", "directories"))
+		}
+		fmt.Fprintf(buf, "%s└── … %s: %d %s, %s\n", indent, desc, files, textutil.Plural(files, "file", "files"), textutil.FormatSize(size))
+	}
+}
+
+func summarize(n *treeNode) string {
+	return fmt.Sprintf("%d %s, %s", n.files, textutil.Plural(n.files, "file", "files"), textutil.FormatSize(n.size))
+}
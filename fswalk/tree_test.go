@@ -0,0 +1,54 @@
+package fswalk
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTree(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		".gitignore":    "ignored/\n",
+		"main.go":       "package main\n",
+		"ignored/x.go":  "package x\n",
+		"pkg/a/a.go":    "package a\n",
+		"pkg/a/deep/d":  "12345",
+		"pkg/b.go":      "package pkg\n",
+		"pkg/c.go":      "package pkg\n",
+		"pkg/d.go":      "package pkg\n",
+		"docs/readme":   strings.Repeat("x", 2048),
+		"docs/more.txt": "",
+	})
+
+	got, err := Tree(root, TreeOptions{MaxDepth: 2, MaxEntries: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := root + `/ (8 files, 2.1 KB)
+├── docs/ (2 files, 2.0 KB)
+│   ├── more.txt (0 B)
+│   └── readme (2.0 KB)
+├── pkg/ (5 files, 51 B)
+│   ├── a/ (2 files, 15 B)
+│   ├── b.go (12 B)
+│   ├── c.go (12 B)
+│   └── … 1 more entry: 1 file, 12 B
+└── main.go (13 B)
+`
+	if got != expect {
+		t.Fatalf("got:\n%s\nexpected:\n%s", got, expect)
+	}
+
+	// A tiny budget forces the depth down to a single level.
+	got, err = Tree(root, TreeOptions{MaxDepth: 5, TokenBudget: 40})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(got, "b.go") || !strings.Contains(got, "pkg/ (5 files") {
+		t.Fatalf("expected depth to be reduced to fit budget, got:\n%s", got)
+	}
+	if len(got) > 40*4 {
+		t.Fatalf("tree exceeds budget: %d bytes", len(got))
+	}
+}
@@ -0,0 +1,161 @@
+// Package fswalk lists and searches project files without external tools.
+// It follows the same defaults as ripgrep: .gitignore, .ignore and
+// .git/info/exclude rules are respected and hidden files are skipped.
+package fswalk
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// WalkFunc is called for every file and directory that is not ignored.
+// rel is the slash separated path relative to the walk root. Returning
+// fs.SkipDir from a directory skips its contents.
+type WalkFunc func(rel string, d fs.DirEntry) error
+
+var ignoreFileNames = []string{".gitignore", ".ignore"}
+
+// Walk walks the tree rooted at root in lexical order, calling fn for each
+// file and directory that is not hidden or ignored. The root itself is
+// not passed to fn.
+func Walk(root string, fn WalkFunc) error {
+	rules, err := ancestorRules(root)
+	if err != nil {
+		return err
+	}
+	return walkDir(root, "", rules, fn)
+}
+
+// ancestorRules loads the rules that apply to root from outside it: the
+// repository's .git/info/exclude and the ignore files in the directories
+// between the repository root and root. Like rg, it stops at the
+// repository root, and loads nothing if root is not in a repository.
+func ancestorRules(root string) (ignoreList, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	// dirs runs from root up to the repository root
+	var (
+		dirs []string
+		git  os.FileInfo
+	)
+	for dir := abs; ; {
+		dirs = append(dirs, dir)
+		if git, err = os.Stat(filepath.Join(dir, ".git")); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+
+	var rules ignoreList
+	load := func(dir, name string) error {
+		more, err := loadIgnoreFile(filepath.Join(dir, name), "")
+		if err != nil {
+			return err
+		}
+		prefix, err := filepath.Rel(dir, abs)
+		if err != nil {
+			return err
+		}
+		if prefix != "." {
+			for _, r := range more {
+				r.prefix = filepath.ToSlash(prefix)
+			}
+		}
+		rules = append(rules, more...)
+		return nil
+	}
+
+	// .git is a file in worktrees and submodules
+	if git.IsDir() {
+		if err := load(dirs[len(dirs)-1], filepath.Join(".git", "info", "exclude")); err != nil {
+			return nil, err
+		}
+	}
+	// root's own ignore files are loaded by walkDir
+	for i := len(dirs) - 1; i > 0; i-- {
+		for _, name := range ignoreFileNames {
+			if err := load(dirs[i], name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return rules, nil
+}
+
+func walkDir(root, rel string, parentRules ignoreList, fn WalkFunc) error {
+	dir := filepath.Join(root, filepath.FromSlash(rel))
+
+	rules := parentRules
+	for _, name := range ignoreFileNames {
+		more, err := loadIgnoreFile(filepath.Join(dir, name), rel)
+		if err != nil {
+			return err
+		}
+		if len(more) > 0 {
+			// copy so sibling directories don't see each other's rules
+			rules = append(rules[:len(rules):len(rules)], more...)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		childRel := path.Join(rel, name)
+		isDir := entry.IsDir()
+		if rules.ignored(childRel, isDir) {
+			continue
+		}
+
+		if !isDir && !entry.Type().IsRegular() {
+			// like rg, don't follow symlinks or list special files
+			continue
+		}
+
+		err := fn(childRel, entry)
+		if isDir && err == fs.SkipDir {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if isDir {
+			err = walkDir(root, childRel, rules, fn)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Files returns the slash separated paths, relative to root, of every
+// regular file that is not hidden or ignored. It is the equivalent of
+// running "rg --files" in root.
+func Files(root string) ([]string, error) {
+	var files []string
+	err := Walk(root, func(rel string, d fs.DirEntry) error {
+		if !d.IsDir() {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,188 @@
+package fswalk
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFiles(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		".gitignore":          "*.log\n/build/\nvendor/\n!keep.log\n",
+		".hidden":             "",
+		".git/config":         "",
+		".git/info/exclude":   "secret.txt\n",
+		"main.go":             "",
+		"debug.log":           "",
+		"keep.log":            "",
+		"secret.txt":          "",
+		"build/out.bin":       "",
+		"cmd/build/main.go":   "",
+		"cmd/vendor/x.go":     "",
+		"pkg/.gitignore":      "gen_*.go\n",
+		"pkg/lib.go":          "",
+		"pkg/gen_lib.go":      "",
+		"pkg/sub/gen_more.go": "",
+		"other/gen_x.go":      "",
+		"docs/a/b/c.md":       "",
+		"docs/.gitignore":     "a/**/*.md\n",
+	})
+
+	got, err := Files(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"cmd/build/main.go",
+		"keep.log",
+		"main.go",
+		"other/gen_x.go",
+		"pkg/lib.go",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestFilesAncestorIgnores(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		".gitignore":                "*.go\n",
+		"repo/.git/info/exclude":    "secret.txt\n",
+		"repo/.gitignore":           "*.log\n/top.txt\n/sub/inner/gen.go\n",
+		"repo/sub/.ignore":          "inner/skip/\n",
+		"repo/sub/inner/a.go":       "",
+		"repo/sub/inner/a.log":      "",
+		"repo/sub/inner/gen.go":     "",
+		"repo/sub/inner/top.txt":    "",
+		"repo/sub/inner/secret.txt": "",
+		"repo/sub/inner/skip/b.go":  "",
+	})
+
+	got, err := Files(filepath.Join(dir, "repo", "sub", "inner"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a.go", "top.txt"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		".gitignore":    "ignored.txt\n",
+		"a.txt":         "foo\nbar foo\nbaz\n",
+		"sub/b.txt":     "nothing\nfoo\n",
+		"ignored.txt":   "foo\n",
+		"binary.dat":    "foo\x00bar\n",
+		"sub/other.txt": "FOO\n",
+	})
+
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+
+	re := regexp.MustCompile("foo")
+
+	got, err := Search(".", re, SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Match{
+		{Path: "./a.txt", Line: 1, Text: "foo"},
+		{Path: "./a.txt", Line: 2, Text: "bar foo"},
+		{Path: "./sub/b.txt", Line: 2, Text: "foo"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v\nwant %+v", got, want)
+	}
+
+	got, err = Search("", re, SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0].Path != "a.txt" {
+		t.Fatalf("expected unprefixed paths, got %+v", got)
+	}
+
+	got, err = Search("sub/b.txt", re, SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []Match{{Path: "sub/b.txt", Line: 2, Text: "foo"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestSearchOptions(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"a.go":      "one\ntwo\nfoo\nthree\nfoo\nfour\nfive\nsix\nfoo\n",
+		"a_test.go": "foo\n",
+		"b.txt":     "foo\n",
+		"c.ts":      "foo\n",
+	})
+
+	re := regexp.MustCompile("foo")
+
+	got, err := Search(root, re, SearchOptions{Globs: []string{"*.{go,ts}", "!*_test.go"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, m := range got {
+		paths = append(paths, filepath.Base(m.Path))
+	}
+	want := []string{"a.go", "a.go", "a.go", "c.ts"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("glob filter got %q want %q", paths, want)
+	}
+
+	got, err = Search(root, re, SearchOptions{Types: []string{"go"}, Context: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	type lineKind struct {
+		line    int
+		context bool
+	}
+	var lines []lineKind
+	for _, m := range got {
+		if filepath.Base(m.Path) == "a.go" {
+			lines = append(lines, lineKind{m.Line, m.Context})
+		}
+	}
+	wantLines := []lineKind{
+		{2, true}, {3, false}, {4, true}, {5, false}, {6, true},
+		{8, true}, {9, false},
+	}
+	if !reflect.DeepEqual(lines, wantLines) {
+		t.Fatalf("context got %v want %v", lines, wantLines)
+	}
+
+	if _, err := Search(root, re, SearchOptions{Types: []string{"nosuchtype"}}); err == nil {
+		t.Fatal("expected error for unknown type")
+	}
+}
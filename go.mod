@@ -7,10 +7,13 @@ require (
 	github.com/chzyer/readline v1.5.1
 	github.com/psanford/claude v0.0.0-20250315183110-28f5729f7efc
 	github.com/spf13/cobra v1.8.1
+	golang.org/x/tools v0.28.0
 )
 
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
-	golang.org/x/sys v0.0.0-20220310020820-b874c991c1a5 // indirect
+	golang.org/x/mod v0.22.0 // indirect
+	golang.org/x/sync v0.10.0 // indirect
+	golang.org/x/sys v0.28.0 // indirect
 )
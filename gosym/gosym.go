@@ -0,0 +1,442 @@
+// Package gosym looks up Go symbols using type information: where a
+// symbol is defined, where it is referenced and which types implement or
+// are implemented by it.
+package gosym
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Location is a position in a source file along with the source text
+// found there.
+type Location struct {
+	Filename string // relative to the working directory when possible
+	Line     int
+	Column   int
+	Snippet  string
+}
+
+func (l Location) String() string {
+	return fmt.Sprintf("%s:%d:%d", l.Filename, l.Line, l.Column)
+}
+
+// Program is a set of type checked packages.
+type Program struct {
+	Fset     *token.FileSet
+	Packages []*packages.Package
+	dir      string
+}
+
+const loadMode = packages.NeedName | packages.NeedFiles | packages.NeedSyntax |
+	packages.NeedTypes | packages.NeedTypesInfo | packages.NeedImports
+
+// Load type checks the packages matching patterns in dir.
+func Load(dir string, patterns ...string) (*Program, error) {
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+	fset := token.NewFileSet()
+	cfg := &packages.Config{
+		Mode:  loadMode,
+		Dir:   dir,
+		Fset:  fset,
+		Tests: true,
+	}
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) == 0 {
+		return nil, fmt.Errorf("no packages found matching %s", strings.Join(patterns, " "))
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Program{
+		Fset:     fset,
+		Packages: pkgs,
+		dir:      absDir,
+	}, nil
+}
+
+// Lookup resolves a symbol given either as a dotted name ("pkg.Func",
+// "pkg.Type.Method", "Type.Field") or as a position ("file.go:12:5").
+func (p *Program) Lookup(symbol string) (types.Object, error) {
+	symbol = strings.TrimSpace(symbol)
+	if symbol == "" {
+		return nil, errors.New("symbol is empty")
+	}
+	if file, line, col, ok := parsePosition(symbol); ok {
+		return p.lookupPosition(file, line, col)
+	}
+	return p.lookupName(symbol)
+}
+
+func parsePosition(s string) (string, int, int, bool) {
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || !strings.HasSuffix(parts[0], ".go") {
+		return "", 0, 0, false
+	}
+	line, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return "", 0, 0, false
+	}
+	col := 1
+	if len(parts) > 2 {
+		col, err = strconv.Atoi(parts[2])
+		if err != nil {
+			return "", 0, 0, false
+		}
+	}
+	return parts[0], line, col, true
+}
+
+func (p *Program) lookupPosition(file string, line, col int) (types.Object, error) {
+	absFile, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pkg := range p.Packages {
+		for _, f := range pkg.Syntax {
+			tf := p.Fset.File(f.Pos())
+			if tf == nil || tf.Name() != absFile {
+				continue
+			}
+			if line < 1 || line > tf.LineCount() {
+				return nil, fmt.Errorf("%s has %d lines", file, tf.LineCount())
+			}
+			pos := tf.LineStart(line) + token.Pos(col-1)
+
+			var found *ast.Ident
+			ast.Inspect(f, func(n ast.Node) bool {
+				if n == nil || found != nil {
+					return false
+				}
+				if pos < n.Pos() || pos > n.End() {
+					return false
+				}
+				if id, ok := n.(*ast.Ident); ok {
+					found = id
+					return false
+				}
+				return true
+			})
+			if found == nil {
+				continue
+			}
+			if obj := pkg.TypesInfo.Defs[found]; obj != nil {
+				return obj, nil
+			}
+			if obj := pkg.TypesInfo.Uses[found]; obj != nil {
+				return obj, nil
+			}
+			return nil, fmt.Errorf("no object found for identifier %s at %s:%d:%d", found.Name, file, line, col)
+		}
+	}
+	return nil, fmt.Errorf("no identifier found at %s:%d:%d (is the file part of a loaded package?)", file, line, col)
+}
+
+func (p *Program) lookupName(symbol string) (types.Object, error) {
+	parts := strings.Split(symbol, ".")
+
+	var candidates []types.Object
+	seen := make(map[string]bool)
+	for _, pkg := range p.Packages {
+		if pkg.Types == nil {
+			continue
+		}
+
+		// Try interpreting the leading part as a package name or import
+		// path, then as a name in this package's scope.
+		rest := parts
+		if matchesPackage(pkg, parts[0]) && len(parts) > 1 {
+			rest = parts[1:]
+		} else if len(parts) > 1 && strings.Contains(symbol, "/") {
+			// import path with a dotted tail, e.g. example.com/x/pkg.Func
+			idx := strings.LastIndex(symbol, "/")
+			tail := strings.SplitN(symbol[idx+1:], ".", 2)
+			if len(tail) != 2 || pkg.PkgPath != symbol[:idx+1]+tail[0] {
+				continue
+			}
+			rest = strings.Split(tail[1], ".")
+		}
+
+		obj := lookupInScope(pkg.Types, rest)
+		if obj == nil {
+			continue
+		}
+		key := objectKey(p.Fset, obj)
+		if !seen[key] {
+			seen[key] = true
+			candidates = append(candidates, obj)
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("symbol %s not found", symbol)
+	case 1:
+		return candidates[0], nil
+	}
+
+	var names []string
+	for _, c := range candidates {
+		names = append(names, fmt.Sprintf("%s (%s)", qualifiedName(c), p.location(c.Pos(), false)))
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("symbol %s is ambiguous, qualify it with a package: %s", symbol, strings.Join(names, ", "))
+}
+
+func matchesPackage(pkg *packages.Package, name string) bool {
+	return pkg.Name == name || pkg.PkgPath == name || strings.HasSuffix(pkg.PkgPath, "/"+name)
+}
+
+func lookupInScope(pkg *types.Package, parts []string) types.Object {
+	obj := pkg.Scope().Lookup(parts[0])
+	if obj == nil {
+		return nil
+	}
+	for _, name := range parts[1:] {
+		// for a type this finds its fields and methods; for a variable,
+		// the fields and methods of the variable's type
+		found, _, _ := types.LookupFieldOrMethod(obj.Type(), true, pkg, name)
+		if found == nil {
+			return nil
+		}
+		obj = found
+	}
+	return obj
+}
+
+// objectKey identifies an object by its declaration position so objects
+// from different type checking passes (such as a package and its test
+// variant) compare equal.
+func objectKey(fset *token.FileSet, obj types.Object) string {
+	pos := fset.Position(obj.Pos())
+	return fmt.Sprintf("%s:%d:%d:%s", pos.Filename, pos.Line, pos.Column, obj.Name())
+}
+
+func qualifiedName(obj types.Object) string {
+	var prefix string
+	if obj.Pkg() != nil {
+		prefix = obj.Pkg().Name() + "."
+	}
+	if fn, ok := obj.(*types.Func); ok {
+		if recv := fn.Type().(*types.Signature).Recv(); recv != nil {
+			return prefix + typeName(recv.Type()) + "." + obj.Name()
+		}
+	}
+	return prefix + obj.Name()
+}
+
+func typeName(t types.Type) string {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	if named, ok := t.(*types.Named); ok {
+		return named.Obj().Name()
+	}
+	return t.String()
+}
+
+// Definition returns the location where obj is declared.
+func (p *Program) Definition(obj types.Object) (Location, error) {
+	if !obj.Pos().IsValid() {
+		return Location{}, fmt.Errorf("%s is a builtin and has no source location", obj.Name())
+	}
+	return p.location(obj.Pos(), true), nil
+}
+
+// References returns every use of obj across the loaded packages, sorted
+// by position. The declaration itself is not included.
+func (p *Program) References(obj types.Object) []Location {
+	target := objectKey(p.Fset, obj)
+
+	seen := make(map[string]bool)
+	var locs []Location
+	for _, pkg := range p.Packages {
+		if pkg.TypesInfo == nil {
+			continue
+		}
+		for id, use := range pkg.TypesInfo.Uses {
+			if objectKey(p.Fset, use) != target {
+				continue
+			}
+			loc := p.location(id.Pos(), false)
+			if !seen[loc.String()] {
+				seen[loc.String()] = true
+				locs = append(locs, loc)
+			}
+		}
+	}
+	sortLocations(locs)
+	return locs
+}
+
+// Implementation is a type or method related to the looked up symbol.
+type Implementation struct {
+	Name     string
+	Location Location
+}
+
+// Implementations returns, for an interface, the named types that
+// implement it; for a concrete type, the interfaces it implements. For a
+// method, the corresponding methods on those types are returned.
+func (p *Program) Implementations(obj types.Object) ([]Implementation, error) {
+	var methodName string
+	typ := obj.Type()
+	if fn, ok := obj.(*types.Func); ok {
+		recv := fn.Type().(*types.Signature).Recv()
+		if recv == nil {
+			return nil, fmt.Errorf("%s is a function, not a type or method", obj.Name())
+		}
+		methodName = fn.Name()
+		typ = recv.Type()
+		if ptr, ok := typ.(*types.Pointer); ok {
+			typ = ptr.Elem()
+		}
+	} else if _, ok := obj.(*types.TypeName); !ok {
+		return nil, fmt.Errorf("%s is not a type or method", obj.Name())
+	}
+
+	iface, isIface := typ.Underlying().(*types.Interface)
+	targetKey := ""
+	if named, ok := typ.(*types.Named); ok {
+		targetKey = objectKey(p.Fset, named.Obj())
+	}
+
+	seen := make(map[string]bool)
+	var out []Implementation
+	for _, pkg := range p.Packages {
+		if pkg.Types == nil {
+			continue
+		}
+		scope := pkg.Types.Scope()
+		for _, name := range scope.Names() {
+			tn, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || tn.IsAlias() || objectKey(p.Fset, tn) == targetKey {
+				continue
+			}
+			candidate := tn.Type()
+			candIface, candIsIface := candidate.Underlying().(*types.Interface)
+
+			var match bool
+			if isIface {
+				if candIsIface || iface.NumMethods() == 0 {
+					continue
+				}
+				match = types.Implements(candidate, iface) || types.Implements(types.NewPointer(candidate), iface)
+			} else {
+				if !candIsIface || candIface.NumMethods() == 0 {
+					continue
+				}
+				match = types.Implements(typ, candIface) || types.Implements(types.NewPointer(typ), candIface)
+			}
+			if !match {
+				continue
+			}
+
+			resultObj := types.Object(tn)
+			if methodName != "" {
+				m, _, _ := types.LookupFieldOrMethod(candidate, true, tn.Pkg(), methodName)
+				if m == nil {
+					continue
+				}
+				resultObj = m
+			}
+
+			key := objectKey(p.Fset, resultObj)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			out = append(out, Implementation{
+				Name:     qualifiedName(resultObj),
+				Location: p.location(resultObj.Pos(), false),
+			})
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Location.String() < out[j].Location.String()
+	})
+	return out, nil
+}
+
+// maxSnippetLines limits the number of lines shown for a definition.
+const maxSnippetLines = 15
+
+func (p *Program) location(pos token.Pos, declaration bool) Location {
+	position := p.Fset.Position(pos)
+	filename := position.Filename
+	if rel, err := filepath.Rel(p.dir, filename); err == nil && !strings.HasPrefix(rel, "..") {
+		filename = rel
+	}
+
+	loc := Location{
+		Filename: filename,
+		Line:     position.Line,
+		Column:   position.Column,
+	}
+
+	content, err := os.ReadFile(position.Filename)
+	if err != nil {
+		return loc
+	}
+	lines := strings.Split(string(content), "\n")
+	if position.Line < 1 || position.Line > len(lines) {
+		return loc
+	}
+
+	if !declaration {
+		loc.Snippet = strings.TrimSpace(lines[position.Line-1])
+		return loc
+	}
+
+	// Show the declaration up to the end of its first block, or a line
+	// with the same indentation as the first line.
+	first := lines[position.Line-1]
+	indent := first[:len(first)-len(strings.TrimLeft(first, " \t"))]
+	end := position.Line
+	if strings.HasSuffix(strings.TrimSpace(first), "{") || strings.HasSuffix(strings.TrimSpace(first), "(") {
+		for end < len(lines) && end-position.Line < maxSnippetLines-1 {
+			end++
+			if strings.HasPrefix(lines[end-1], indent+"}") || strings.HasPrefix(lines[end-1], indent+")") {
+				break
+			}
+		}
+	}
+	snippet := strings.Join(lines[position.Line-1:end], "\n")
+	if end-position.Line == maxSnippetLines-1 {
+		snippet += "\n…"
+	}
+	loc.Snippet = snippet
+	return loc
+}
+
+func sortLocations(locs []Location) {
+	sort.Slice(locs, func(i, j int) bool {
+		if locs[i].Filename != locs[j].Filename {
+			return locs[i].Filename < locs[j].Filename
+		}
+		if locs[i].Line != locs[j].Line {
+			return locs[i].Line < locs[j].Line
+		}
+		return locs[i].Column < locs[j].Column
+	})
+}
@@ -0,0 +1,129 @@
+package gosym
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeModule(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.22\n",
+		"store/store.go": `package store
+
+type Getter interface {
+	Get(key string) string
+}
+
+type Store struct {
+	Name string
+}
+
+func (s *Store) Get(key string) string {
+	return s.Name + key
+}
+`,
+		"cache/cache.go": `package cache
+
+type Cache struct{}
+
+func (c Cache) Get(key string) string { return key }
+`,
+		"main.go": `package main
+
+import (
+	"example.com/m/cache"
+	"example.com/m/store"
+)
+
+func main() {
+	s := &store.Store{Name: "x"}
+	var g store.Getter = s
+	g.Get("a")
+	s.Get("b")
+	cache.Cache{}.Get("c")
+}
+`,
+	}
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestSymbols(t *testing.T) {
+	root := writeModule(t)
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+
+	prog, err := Load(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := prog.Lookup("Get"); err == nil {
+		t.Fatal("expected an error looking up an unqualified method name")
+	}
+	if _, err := prog.Lookup("Cache.Get"); err != nil {
+		t.Fatalf("lookup Cache.Get: %s", err)
+	}
+
+	obj, err := prog.Lookup("store.Store.Get")
+	if err != nil {
+		t.Fatal(err)
+	}
+	def, err := prog.Definition(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def.String() != "store/store.go:11:17" || !strings.Contains(def.Snippet, "return s.Name + key") {
+		t.Fatalf("unexpected definition %s:\n%s", def, def.Snippet)
+	}
+
+	// s.Get is a reference to Store.Get but g.Get and Cache.Get are not
+	refs := prog.References(obj)
+	if len(refs) != 1 || refs[0].String() != "main.go:12:4" {
+		t.Fatalf("unexpected references %v", refs)
+	}
+
+	// the position form resolves the identifier under the cursor
+	obj, err = prog.Lookup("main.go:11:4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	impls, err := prog.Implementations(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, impl := range impls {
+		names = append(names, impl.Name)
+	}
+	if strings.Join(names, ",") != "cache.Cache.Get,store.Store.Get" {
+		t.Fatalf("unexpected implementations %v", names)
+	}
+
+	obj, err = prog.Lookup("store.Store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	impls, err = prog.Implementations(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(impls) != 1 || impls[0].Name != "store.Getter" {
+		t.Fatalf("unexpected interfaces for Store: %v", impls)
+	}
+}
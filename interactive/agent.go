@@ -0,0 +1,133 @@
+package interactive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/psanford/claude"
+	"github.com/psanford/claude/clientiface"
+	"github.com/psanford/code-buddy/accumulator"
+)
+
+var (
+	errAgentTurnBudget  = errors.New("turn budget exhausted")
+	errAgentTokenBudget = errors.New("token budget exhausted")
+)
+
+// agent runs a conversation without user interaction. Tool calls are
+// executed without asking for approval, so an agent must only be given
+// tools that are safe to run unattended, confined to root.
+type agent struct {
+	runner      *Runner // tool settings such as CatMaxLines
+	client      clientiface.Client
+	model       string
+	system      string
+	tools       []string
+	root        string    // tools may only use paths inside root
+	maxTurns    int       // model requests before giving up
+	maxTokens   int       // input plus output tokens before giving up; 0 is no limit
+	log         io.Writer // tool calls are reported here if set
+	debugLogger *slog.Logger
+}
+
+// run sends prompt and executes tool calls until the model replies without
+// one. It returns the text of that final reply.
+func (a *agent) run(ctx context.Context, prompt string) (string, error) {
+	turns := []claude.MessageTurn{
+		{
+			Role:    "user",
+			Content: []claude.TurnContent{claude.TextContent(prompt)},
+		},
+	}
+
+	var tokens int
+	for i := 0; i < a.maxTurns; i++ {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		if a.maxTokens > 0 && tokens >= a.maxTokens {
+			return "", errAgentTokenBudget
+		}
+
+		req := &claude.MessageRequest{
+			Model:         a.model,
+			System:        a.system,
+			MaxTokens:     maxTokensForModel(a.model),
+			StopSequences: []string{commandPrefix + ",invoke"},
+			Messages:      turns,
+		}
+		acc := accumulator.New(a.client, accumulator.WithDebugLogger(a.debugLogger))
+		respMeta, err := acc.Complete(ctx, req)
+		if err != nil {
+			return "", err
+		}
+		tokens += respMeta.Usage.InputTokens + respMeta.Usage.OutputTokens
+
+		var (
+			text         strings.Builder
+			functionCall *FunctionCall
+			parseErr     error
+			turnContents = make([]claude.TurnContent, 0, len(respMeta.Content))
+		)
+		for _, content := range respMeta.Content {
+			blk := content.(*accumulator.ContentBlock)
+			if blk.Type() != "text" {
+				turnContents = append(turnContents, content)
+				continue
+			}
+			fc, contentUntilFirstFunCall, err := parseCommand(blk.Text)
+			turnContents = append(turnContents, claude.TextContent(contentUntilFirstFunCall))
+			text.WriteString(contentUntilFirstFunCall)
+			if err == io.EOF {
+				continue
+			} else if err != nil {
+				parseErr = err
+			}
+			functionCall = fc
+		}
+		turns = append(turns, claude.MessageTurn{Role: "assistant", Content: turnContents})
+
+		if functionCall == nil && parseErr == nil {
+			return text.String(), nil
+		}
+
+		result := a.runTool(ctx, functionCall, parseErr)
+		turns = append(turns, result.MessageTurn)
+	}
+
+	return "", errAgentTurnBudget
+}
+
+func (a *agent) runTool(ctx context.Context, functionCall *FunctionCall, parseErr error) turnContent {
+	if parseErr != nil {
+		return functionResultTurn("", parseErr.Error(), 1)
+	}
+	if !toolAllowed(a.tools, functionCall.Name) {
+		return functionResultTurn("", fmt.Sprintf("tool %s is not available", functionCall.Name), 1)
+	}
+
+	paramMap := make(map[string]string)
+	for _, p := range functionCall.Parameters {
+		paramMap[p.Name] = string(p.Value)
+	}
+	cmd, err := a.runner.newCmd(functionCall.Name, paramMap)
+	if err != nil {
+		return functionResultTurn("", err.Error(), 1)
+	}
+	if err := checkToolArgs(a.root, cmd, paramMap); err != nil {
+		return functionResultTurn("", err.Error(), 1)
+	}
+
+	if a.log != nil {
+		fmt.Fprintf(a.log, "%s\n", cmd.PrettyCommand())
+	}
+	out, err := runCmd(ctx, cmd)
+	if err != nil {
+		return functionResultTurn(out, err.Error(), 1)
+	}
+	return functionResultTurn(out, "", 0)
+}
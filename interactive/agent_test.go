@@ -0,0 +1,128 @@
+package interactive
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/psanford/claude"
+	"github.com/psanford/claude/clientiface"
+)
+
+// fakeClient replies with a scripted text message for each request.
+type fakeClient struct {
+	replies  []string
+	requests []*claude.MessageRequest
+	tokens   int // input tokens reported for each request
+}
+
+type fakeResponse chan claude.MessageEvent
+
+func (r fakeResponse) Responses() <-chan claude.MessageEvent { return r }
+
+func (c *fakeClient) Message(ctx context.Context, req *claude.MessageRequest, options ...clientiface.Option) (claude.MessageResponse, error) {
+	c.requests = append(c.requests, req)
+	reply := c.replies[0]
+	c.replies = c.replies[1:]
+
+	ch := make(fakeResponse, 3)
+	start := &claude.ContentBlockStart{}
+	start.ContentBlock.Type = "text"
+	start.ContentBlock.Text = reply
+	msgStart := &claude.MessageStart{}
+	msgStart.Usage.InputTokens = c.tokens
+	ch <- claude.MessageEvent{Data: msgStart}
+	ch <- claude.MessageEvent{Data: start}
+	ch <- claude.MessageEvent{Data: &claude.ContentBlockStop{}}
+	close(ch)
+	return ch, nil
+}
+
+func fakeFunctionCall(name string, params ...string) string {
+	var buf strings.Builder
+	buf.WriteString("Let me check.\n")
+	buf.WriteString(commandPrefix + ",function," + name + "\n")
+	for i := 0; i+1 < len(params); i += 2 {
+		buf.WriteString(commandPrefix + ",parameter," + params[i] + "\n")
+		buf.WriteString(params[i+1] + "\n")
+		buf.WriteString(commandPrefix + ",end_parameter\n")
+	}
+	buf.WriteString(commandPrefix + ",end_function\n")
+	return buf.String()
+}
+
+func TestAgentRun(t *testing.T) {
+	dir := t.TempDir()
+	chdir(t, dir)
+
+	os.WriteFile("a.txt", []byte("hello\n"), 0644)
+	root, _ := filepath.EvalSymlinks(dir)
+
+	client := &fakeClient{
+		replies: []string{
+			fakeFunctionCall("cat", "filename", "a.txt"),
+			fakeFunctionCall("write_file", "filename", "a.txt", "content", "bye"),
+			"All done.",
+		},
+	}
+	a := &agent{
+		runner:   &Runner{},
+		client:   client,
+		model:    "test-model",
+		system:   "system",
+		tools:    readOnlyTools,
+		root:     root,
+		maxTurns: 5,
+	}
+
+	reply, err := a.run(context.Background(), "read a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != "All done." {
+		t.Fatalf("reply=%q", reply)
+	}
+
+	if len(client.requests) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(client.requests))
+	}
+	msgs := client.requests[2].Messages
+	if len(msgs) != 5 {
+		t.Fatalf("expected 5 messages in the last request, got %d", len(msgs))
+	}
+	if got := msgs[2].Content[0].TextContent(); !strings.Contains(got, "hello") || !strings.Contains(got, "<exit_code>0</exit_code>") {
+		t.Errorf("cat result: %s", got)
+	}
+	if got := msgs[4].Content[0].TextContent(); !strings.Contains(got, "tool write_file is not available") {
+		t.Errorf("write_file result: %s", got)
+	}
+	if content, _ := os.ReadFile("a.txt"); string(content) != "hello\n" {
+		t.Errorf("a.txt was modified: %q", content)
+	}
+
+	client.replies = []string{
+		fakeFunctionCall("cat", "filename", "a.txt"),
+		fakeFunctionCall("cat", "filename", "a.txt"),
+	}
+	a.maxTurns = 2
+	if _, err := a.run(context.Background(), "loop"); err != errAgentTurnBudget {
+		t.Fatalf("expected turn budget error, got %v", err)
+	}
+
+	client.replies = []string{
+		fakeFunctionCall("cat", "filename", "a.txt"),
+		fakeFunctionCall("cat", "filename", "a.txt"),
+	}
+	client.requests = nil
+	client.tokens = 600
+	a.maxTurns = 5
+	a.maxTokens = 1000
+	if _, err := a.run(context.Background(), "loop"); err != errAgentTokenBudget {
+		t.Fatalf("expected token budget error, got %v", err)
+	}
+	if len(client.requests) != 2 {
+		t.Fatalf("expected 2 requests before the token budget ran out, got %d", len(client.requests))
+	}
+}
@@ -0,0 +1,383 @@
+package interactive
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/psanford/code-buddy/commitmsg"
+)
+
+const sessionTrailer = "Code-Buddy-Session"
+
+// autoCommitter records the files the assistant changes during a session
+// and commits them, either after each request (when enabled) or on /commit.
+type autoCommitter struct {
+	session string
+	// enabled commits each batch of approved edits automatically
+	enabled bool
+	// branch commits on code-buddy/<session> instead of the current branch
+	branch bool
+	gen    *commitmsg.Generator
+
+	pending map[string]bool
+	// userChanged holds files that had uncommitted changes before the
+	// assistant edited them; they are left for the user to commit
+	userChanged map[string]bool
+	// commits made by this session, oldest first
+	commits []string
+}
+
+func newAutoCommitter(session string, enabled, branch bool, gen *commitmsg.Generator) *autoCommitter {
+	return &autoCommitter{
+		session:     session,
+		enabled:     enabled,
+		branch:      branch,
+		gen:         gen,
+		pending:     make(map[string]bool),
+		userChanged: make(map[string]bool),
+	}
+}
+
+func (a *autoCommitter) branchName() string {
+	return "code-buddy/" + a.session
+}
+
+// beforeEdit is called with the files an approved command is about to
+// modify. When auto-commit is enabled, files with uncommitted changes that
+// the assistant didn't make are remembered so the user's changes aren't
+// committed with its own. Without auto-commit, /commit commits the files
+// as they are.
+func (a *autoCommitter) beforeEdit(files []string) {
+	if !a.enabled {
+		return
+	}
+	for _, f := range files {
+		if f == "" {
+			continue
+		}
+		f = filepath.Clean(f)
+		if a.pending[f] {
+			// already changed by the assistant since the last commit
+			continue
+		}
+		out, err := gitOutput("status", "--porcelain", "--untracked-files=all", "--", f)
+		if err == nil && len(bytes.TrimSpace(out)) > 0 {
+			a.userChanged[f] = true
+		} else {
+			delete(a.userChanged, f)
+		}
+	}
+}
+
+// track records files modified by an approved command.
+func (a *autoCommitter) track(files []string) {
+	for _, f := range files {
+		if f != "" {
+			a.pending[filepath.Clean(f)] = true
+		}
+	}
+}
+
+// afterRequest commits the pending changes if auto-commit is enabled.
+// userPrompt is passed to the model as context for the commit message.
+func (a *autoCommitter) afterRequest(ctx context.Context, userPrompt string) {
+	if !a.enabled || len(a.pending) == 0 {
+		return
+	}
+	reqContext := "The change was made by a coding assistant in response to this request:\n" + userPrompt
+	hash, subject, err := a.commitPending(ctx, "", reqContext)
+	if err != nil {
+		fmt.Printf("auto-commit err: %s\n", err)
+		return
+	}
+	if hash != "" {
+		fmt.Printf("committed %s %s\n", hash, subject)
+	}
+}
+
+// commitPending commits the pending files. If message is empty one is
+// generated, using reqContext as additional context for the model. It
+// returns an empty hash if there was nothing to commit.
+func (a *autoCommitter) commitPending(ctx context.Context, message, reqContext string) (string, string, error) {
+	paths, err := a.committablePaths()
+	if err != nil {
+		return "", "", err
+	}
+	if len(paths) == 0 {
+		a.clearPending()
+		return "", "", nil
+	}
+
+	if a.branch {
+		if err := a.switchToSessionBranch(paths); err != nil {
+			return "", "", err
+		}
+	}
+
+	addArgs := append([]string{"add", "-A", "--"}, paths...)
+	if _, err := gitOutput(addArgs...); err != nil {
+		return "", "", err
+	}
+
+	diffArgs := append([]string{"diff", "--cached", "--"}, paths...)
+	diff, err := gitOutput(diffArgs...)
+	if err != nil {
+		return "", "", err
+	}
+	if len(diff) == 0 {
+		a.clearPending()
+		return "", "", nil
+	}
+
+	if message == "" {
+		message = a.message(ctx, string(diff), reqContext, paths)
+	}
+
+	commitArgs := append([]string{"commit", "-q", "-F", "-", "--"}, paths...)
+	if _, err := gitWithInput(a.withTrailer(message), commitArgs...); err != nil {
+		return "", "", err
+	}
+	a.clearPending()
+
+	hash, err := gitOutput("rev-parse", "HEAD")
+	if err != nil {
+		return "", "", err
+	}
+	full := strings.TrimSpace(string(hash))
+	a.commits = append(a.commits, full)
+	return full[:min(len(full), 7)], firstLine(message), nil
+}
+
+func (a *autoCommitter) clearPending() {
+	a.pending = make(map[string]bool)
+	a.userChanged = make(map[string]bool)
+}
+
+// committablePaths filters the pending files down to paths git can
+// commit: inside the work tree, not ignored, either present or tracked,
+// and without uncommitted changes from the user.
+func (a *autoCommitter) committablePaths() ([]string, error) {
+	root := gitRoot(".")
+	if root == "" {
+		return nil, errors.New("not in a git repository")
+	}
+
+	var paths []string
+	for p := range a.pending {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			continue
+		}
+		if resolved, err := filepath.EvalSymlinks(filepath.Dir(abs)); err == nil {
+			abs = filepath.Join(resolved, filepath.Base(abs))
+		}
+		if rel, err := filepath.Rel(root, abs); err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if exec.Command("git", "check-ignore", "-q", "--", p).Run() == nil {
+			continue
+		}
+		if a.userChanged[p] {
+			fmt.Printf("not committing %s: it had uncommitted changes before the assistant edited it; commit it yourself\n", p)
+			continue
+		}
+		if _, err := os.Lstat(p); err != nil {
+			// removed: only commit the removal if git knows about it
+			out, err := gitOutput("ls-files", "--", p)
+			if err != nil || len(out) == 0 {
+				continue
+			}
+		}
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// switchToSessionBranch switches to the session branch before committing
+// paths, creating it from HEAD if needed. Uncommitted changes carry over
+// to the branch, so it refuses while files other than paths are modified.
+func (a *autoCommitter) switchToSessionBranch(paths []string) error {
+	current, err := gitOutput("branch", "--show-current")
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(string(current)) == a.branchName() {
+		return nil
+	}
+
+	statusArgs := []string{"status", "--porcelain", "--untracked-files=no", "--", ":/"}
+	for _, p := range paths {
+		statusArgs = append(statusArgs, ":(exclude)"+p)
+	}
+	out, err := gitOutput(statusArgs...)
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(out)) > 0 {
+		return fmt.Errorf("not switching to branch %s: uncommitted changes that aren't the assistant's would move with it; commit or stash them first:\n%s", a.branchName(), strings.TrimRight(string(out), "\n"))
+	}
+	if _, err := gitOutput("rev-parse", "--verify", "--quiet", "refs/heads/"+a.branchName()); err == nil {
+		_, err = gitOutput("switch", a.branchName())
+		return err
+	}
+	if _, err := gitOutput("switch", "-c", a.branchName()); err != nil {
+		return err
+	}
+	fmt.Printf("switched to branch %s\n", a.branchName())
+	return nil
+}
+
+func (a *autoCommitter) message(ctx context.Context, diff, reqContext string, paths []string) string {
+	if a.gen != nil {
+		msg, err := a.gen.Message(ctx, commitmsg.Request{
+			Diff:     diff,
+			Context:  reqContext,
+			Examples: commitmsg.RecentMessages(5),
+		})
+		if err == nil && strings.TrimSpace(msg) != "" {
+			return msg
+		}
+		if err != nil {
+			fmt.Printf("generate commit message err: %s\n", err)
+		}
+	}
+	return fmt.Sprintf("Update %s\n", strings.Join(paths, ", "))
+}
+
+func (a *autoCommitter) withTrailer(message string) string {
+	return strings.TrimRight(message, "\n") + "\n\n" + sessionTrailer + ": " + a.session + "\n"
+}
+
+// squash implements /commit: it combines this session's commits and any
+// pending changes into a single commit.
+func (a *autoCommitter) squash(ctx context.Context, message string) error {
+	if len(a.commits) == 0 {
+		hash, subject, err := a.commitPending(ctx, message, "")
+		if err != nil {
+			return err
+		}
+		if hash == "" {
+			return errors.New("no changes made by the assistant to commit")
+		}
+		fmt.Printf("committed %s %s\n", hash, subject)
+		return nil
+	}
+
+	// The message doesn't matter, this commit is squashed below.
+	if _, _, err := a.commitPending(ctx, "Pending assistant changes\n", ""); err != nil {
+		return err
+	}
+	if err := a.checkCommitsAtHead(); err != nil {
+		return err
+	}
+
+	first := a.commits[0]
+	if _, err := gitOutput("rev-parse", "--verify", "--quiet", first+"^"); err != nil {
+		return errors.New("cannot squash a commit with no parent")
+	}
+
+	if message == "" {
+		out, err := gitOutput("diff", "--name-only", first+"^", "HEAD")
+		if err != nil {
+			return err
+		}
+		paths := strings.Fields(string(out))
+
+		diff, err := gitOutput("diff", first+"^", "HEAD")
+		if err != nil {
+			return err
+		}
+		var subjects []string
+		for _, c := range a.commits {
+			s, err := gitOutput("log", "-n", "1", "--format=%s", c)
+			if err == nil {
+				subjects = append(subjects, strings.TrimSpace(string(s)))
+			}
+		}
+		message = a.message(ctx, string(diff), "The individual commits being combined were:\n"+strings.Join(subjects, "\n"), paths)
+	}
+
+	// Build the squashed commit from HEAD's tree directly so the index
+	// and any changes the user has staged are left alone.
+	hash, err := gitWithInput(a.withTrailer(message), "commit-tree", "HEAD^{tree}", "-p", first+"^", "-F", "-")
+	if err != nil {
+		return err
+	}
+	full := strings.TrimSpace(string(hash))
+	if _, err := gitOutput("reset", "-q", "--soft", full); err != nil {
+		return err
+	}
+	fmt.Printf("squashed %d commits into %s %s\n", len(a.commits), full[:min(len(full), 7)], firstLine(message))
+	a.commits = []string{full}
+	return nil
+}
+
+// undo implements /git-undo: it removes the most recent commit made by
+// this session. If it is still HEAD it is reset away, keeping unrelated
+// uncommitted changes; otherwise a revert commit is created.
+func (a *autoCommitter) undo() error {
+	if len(a.commits) == 0 {
+		return errors.New("no commits from this session to undo")
+	}
+	last := a.commits[len(a.commits)-1]
+
+	head, err := gitOutput("rev-parse", "HEAD")
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(string(head)) == last {
+		if _, err := gitOutput("reset", "-q", "--keep", "HEAD~1"); err != nil {
+			return err
+		}
+		fmt.Printf("removed commit %s\n", last[:7])
+	} else {
+		if _, err := gitOutput("revert", "--no-edit", last); err != nil {
+			return err
+		}
+		fmt.Printf("reverted commit %s\n", last[:7])
+	}
+	a.commits = a.commits[:len(a.commits)-1]
+	return nil
+}
+
+// checkCommitsAtHead verifies the session's commits are the most recent
+// commits on the current branch, so squashing them doesn't rewrite anyone
+// else's work.
+func (a *autoCommitter) checkCommitsAtHead() error {
+	out, err := gitOutput("rev-list", "-n", fmt.Sprint(len(a.commits)), "HEAD")
+	if err != nil {
+		return err
+	}
+	recent := strings.Fields(string(out))
+	for i, c := range a.commits {
+		if len(recent) != len(a.commits) || recent[len(recent)-1-i] != c {
+			return errors.New("this session's commits are no longer the most recent commits on the branch; squash them manually")
+		}
+	}
+	return nil
+}
+
+func gitWithInput(input string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Stdin = strings.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
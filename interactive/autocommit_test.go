@@ -0,0 +1,149 @@
+package interactive
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestAutoCommitter(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir := t.TempDir()
+	chdir(t, dir)
+
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	git := func(args ...string) string {
+		t.Helper()
+		out, err := exec.Command("git", args...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %s %s", strings.Join(args, " "), err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	writeFile := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "-q", "-b", "main")
+	writeFile("user.txt", "user\n")
+	writeFile("old.txt", "old\n")
+	writeFile(".gitignore", "ignored.txt\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "initial")
+	base := git("rev-parse", "HEAD")
+
+	ctx := context.Background()
+	c := newAutoCommitter("sess1", true, false, nil)
+
+	// the user's own uncommitted change must not be included
+	writeFile("user.txt", "user edit\n")
+
+	edited := []string{"a.txt", "ignored.txt", "old.txt", "never-existed.txt", "user.txt"}
+	c.beforeEdit(edited)
+	writeFile("a.txt", "a\n")
+	writeFile("ignored.txt", "x\n")
+	os.Remove("old.txt")
+	// nor may the assistant's edit to a file the user had already changed
+	writeFile("user.txt", "user edit\nassistant edit\n")
+	c.track(edited)
+	c.afterRequest(ctx, "add a")
+
+	if got := git("log", "-1", "--format=%B"); !strings.Contains(got, "Update a.txt, old.txt") || !strings.Contains(got, "Code-Buddy-Session: sess1") {
+		t.Fatalf("unexpected commit message: %q", got)
+	}
+	if got := git("show", "--name-status", "--format=", "HEAD"); got != "A\ta.txt\nD\told.txt" {
+		t.Fatalf("unexpected commit contents: %q", got)
+	}
+	if got := git("status", "--porcelain"); got != "M user.txt" {
+		t.Fatalf("unexpected status: %q", got)
+	}
+	writeFile("user.txt", "user edit\n")
+
+	// a second edit in the same request is still the assistant's own
+	for _, content := range []string{"b\n", "b2\n"} {
+		c.beforeEdit([]string{"b.txt"})
+		writeFile("b.txt", content)
+		c.track([]string{"b.txt"})
+	}
+	c.afterRequest(ctx, "add b")
+	if got := git("show", "HEAD:b.txt"); got != "b2" {
+		t.Fatalf("committed b.txt=%q", got)
+	}
+	if len(c.commits) != 2 {
+		t.Fatalf("expected 2 commits, got %d", len(c.commits))
+	}
+
+	// squash both commits plus a pending change into one
+	writeFile("a.txt", "a2\n")
+	c.track([]string{"a.txt"})
+	if err := c.squash(ctx, "Add a and b\n"); err != nil {
+		t.Fatal(err)
+	}
+	if got := git("rev-list", "--count", base+"..HEAD"); got != "1" {
+		t.Fatalf("expected a single commit after squash, got %s", got)
+	}
+	if got := git("log", "-1", "--format=%B"); got != "Add a and b\n\nCode-Buddy-Session: sess1" {
+		t.Fatalf("unexpected squashed message: %q", got)
+	}
+	if got := git("show", "HEAD:a.txt"); got != "a2" {
+		t.Fatalf("squashed commit has a.txt=%q", got)
+	}
+	if got := git("status", "--porcelain"); got != "M user.txt" {
+		t.Fatalf("unexpected status after squash: %q", got)
+	}
+
+	if err := c.undo(); err != nil {
+		t.Fatal(err)
+	}
+	if got := git("rev-parse", "HEAD"); got != base {
+		t.Fatalf("undo left HEAD at %s, want %s", got, base)
+	}
+	if _, err := os.Stat("a.txt"); err == nil {
+		t.Fatalf("undo should remove a.txt")
+	}
+	if got, _ := os.ReadFile("user.txt"); string(got) != "user edit\n" {
+		t.Fatalf("undo changed the user's file: %q", got)
+	}
+	if err := c.undo(); err == nil {
+		t.Fatalf("expected error with nothing left to undo")
+	}
+
+	// branch mode commits on a session branch, but won't carry the
+	// user's uncommitted changes over to it
+	b := newAutoCommitter("sess2", true, true, nil)
+	b.beforeEdit([]string{"c.txt"})
+	writeFile("c.txt", "c\n")
+	b.track([]string{"c.txt"})
+	b.afterRequest(ctx, "add c")
+	if got := git("branch", "--show-current"); got != "main" {
+		t.Fatalf("switched to %q with the user's changes in the tree", got)
+	}
+	git("checkout", "--", "user.txt")
+	b.afterRequest(ctx, "add c")
+	if got := git("branch", "--show-current"); got != "code-buddy/sess2" {
+		t.Fatalf("expected session branch, on %q", got)
+	}
+	if got := git("rev-parse", "main"); got != base {
+		t.Fatalf("main moved to %s", got)
+	}
+
+	// without auto-commit nothing is checked before edits
+	writeFile("user.txt", "user edit\n")
+	d := newAutoCommitter("sess3", false, false, nil)
+	d.beforeEdit([]string{"user.txt"})
+	if len(d.userChanged) != 0 {
+		t.Errorf("userChanged = %v with auto-commit disabled", d.userChanged)
+	}
+}
@@ -3,13 +3,21 @@ package interactive
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 	"unicode/utf8"
+
+	"github.com/psanford/code-buddy/fswalk"
 )
 
 type ListFilesArgs struct {
@@ -24,13 +32,66 @@ var cmdCombinedOutput = func(name string, arg ...string) ([]byte, error) {
 	return exec.Command(name, arg...).CombinedOutput()
 }
 
+// errCmdTimeout is returned by runCmdTimeout when the command is killed
+// for exceeding its timeout.
+var errCmdTimeout = errors.New("command timed out")
+
+// cmdWaitDelay is how long to wait for a command's output to close after
+// it was killed or exited, in case a process it started still holds it.
+const cmdWaitDelay = time.Second
+
+// runCmdTimeout runs a command, killing it and any processes it started
+// if it runs longer than timeout. Stdout and stderr are returned separately.
+func runCmdTimeout(timeout time.Duration, name string, arg ...string) (stdout, stderr []byte, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var outBuf, errBuf bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, arg...)
+	cmd.Stdout = &outBuf
+	cmd.Stderr = &errBuf
+	// go test and go build start processes of their own, such as test
+	// binaries; a timeout must kill those too.
+	killProcessGroup(cmd)
+	cmd.WaitDelay = cmdWaitDelay
+	err = cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("%w after %s", errCmdTimeout, timeout)
+	}
+	return outBuf.Bytes(), errBuf.Bytes(), err
+}
+
+var rgAvailable = func() bool {
+	_, err := exec.LookPath("rg")
+	return err == nil
+}
+
+// projectFiles returns the output of "rg --files". If rg is not installed
+// an equivalent listing is produced with fswalk.
+func projectFiles() ([]byte, error) {
+	if rgAvailable() {
+		return cmdCombinedOutput("rg", "--files")
+	}
+
+	files, err := fswalk.Files(".")
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	for _, f := range files {
+		buf.WriteString(f)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
 func (a *ListFilesArgs) Run() (string, error) {
 	regx, err := regexp.Compile("(?m)" + a.Pattern)
 	if err != nil {
 		return "", err
 	}
 
-	cmdOut, err := cmdCombinedOutput("rg", "--files")
+	cmdOut, err := projectFiles()
 	if err != nil {
 		return "", err
 	}
@@ -53,35 +114,348 @@ func (a *ListFilesArgs) Run() (string, error) {
 	return outBuf.String(), nil
 }
 
+// defaultRGMaxMatches is the number of matches rg returns when the
+// caller doesn't set a limit.
+const defaultRGMaxMatches = 200
+
 type RGArgs struct {
-	Pattern   string `json:"pattern"`
-	Directory string `json:"directory"`
+	Pattern      string   `json:"pattern"`
+	Directory    string   `json:"directory"`
+	Globs        []string `json:"glob"`
+	Types        []string `json:"type"`
+	Context      int      `json:"context"`
+	IgnoreCase   bool     `json:"ignore_case"`
+	FixedStrings bool     `json:"fixed_strings"`
+	MaxMatches   int      `json:"max_matches"`
+}
+
+// args returns the rg arguments for the search. Values from the model are
+// attached to their flags or placed after "--", so none of them can be
+// parsed as another flag such as --pre=<command>.
+func (a *RGArgs) args() []string {
+	var args []string
+	if a.Context > 0 {
+		args = append(args, "-C", strconv.Itoa(a.Context))
+	}
+	if a.IgnoreCase {
+		args = append(args, "-i")
+	}
+	if a.FixedStrings {
+		args = append(args, "-F")
+	}
+	for _, g := range a.Globs {
+		args = append(args, "--glob="+g)
+	}
+	for _, t := range a.Types {
+		args = append(args, "--type="+t)
+	}
+	args = append(args, "-e", a.Pattern)
+	if a.Directory != "" {
+		args = append(args, "--", a.Directory)
+	}
+	return args
 }
 
 func (a *RGArgs) PrettyCommand() string {
-	return fmt.Sprintf("rg %s %s", a.Pattern, a.Directory)
+	quoted := a.args()
+	for i, arg := range quoted {
+		if arg == "" || strings.ContainsAny(arg, " \t*?[]{}!$'\"\\|&;<>()") {
+			quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+	}
+	return fmt.Sprintf("rg -n %s", strings.Join(quoted, " "))
 }
 
 func (a *RGArgs) Run() (string, error) {
-	cmdOut, err := cmdCombinedOutput("rg", a.Pattern, a.Directory)
+	var (
+		matches []fswalk.Match
+		err     error
+	)
+	if rgAvailable() {
+		matches, err = a.rgSearch()
+	} else {
+		matches, err = a.search()
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var singleFile bool
+	if info, err := os.Stat(a.Directory); err == nil && !info.IsDir() {
+		singleFile = true
+	}
+
+	maxMatches := a.MaxMatches
+	if maxMatches <= 0 {
+		maxMatches = defaultRGMaxMatches
+	}
+
+	return formatMatches(matches, singleFile, a.Context > 0, maxMatches), nil
+}
+
+// rgSearch runs rg with --json output so that its results can be
+// formatted and truncated the same way as the fallback searcher's.
+func (a *RGArgs) rgSearch() ([]fswalk.Match, error) {
+	cmdOut, err := cmdCombinedOutput("rg", append([]string{"--json"}, a.args()...)...)
+
+	var (
+		matches []fswalk.Match
+		errMsgs []string
+	)
+	for _, line := range bytes.Split(cmdOut, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var ev rgJSONEvent
+		if jsonErr := json.Unmarshal(line, &ev); jsonErr != nil {
+			// rg writes errors to stderr, which is interleaved with the json
+			errMsgs = append(errMsgs, string(line))
+			continue
+		}
+		if ev.Type != "match" && ev.Type != "context" {
+			continue
+		}
+		matches = append(matches, fswalk.Match{
+			Path:    ev.Data.Path.Text,
+			Line:    ev.Data.LineNumber,
+			Text:    strings.TrimRight(ev.Data.Lines.Text, "\r\n"),
+			Context: ev.Type == "context",
+		})
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		// exit code 1 means no matches
+		return nil, nil
+	} else if err != nil && len(matches) == 0 {
+		return nil, fmt.Errorf("rg err: %w: %s", err, strings.Join(errMsgs, "\n"))
+	}
+
+	return matches, nil
+}
+
+type rgJSONEvent struct {
+	Type string `json:"type"`
+	Data struct {
+		Path struct {
+			Text string `json:"text"`
+		} `json:"path"`
+		Lines struct {
+			Text string `json:"text"`
+		} `json:"lines"`
+		LineNumber int `json:"line_number"`
+	} `json:"data"`
+}
+
+// search is the fallback used when rg is not installed.
+func (a *RGArgs) search() ([]fswalk.Match, error) {
+	pattern := a.Pattern
+	if a.FixedStrings {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if a.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
 	if err != nil {
-		return fmt.Sprintf("cmd err:%s output:%s", err, cmdOut), nil
+		return nil, err
+	}
+
+	return fswalk.Search(a.Directory, re, fswalk.SearchOptions{
+		Context: a.Context,
+		Globs:   a.Globs,
+		Types:   a.Types,
+	})
+}
+
+// formatMatches renders matches like "rg -n": "path:line:text" for
+// matching lines and "path-line-text" for context lines, with "--"
+// between non-adjacent groups. At most maxMatches matching lines are
+// shown, followed by a summary of what was omitted.
+func formatMatches(matches []fswalk.Match, singleFile, withContext bool, maxMatches int) string {
+	var (
+		totalMatches int
+		files        = make(map[string]bool)
+	)
+	for _, m := range matches {
+		if !m.Context {
+			totalMatches++
+			files[m.Path] = true
+		}
 	}
 
-	return string(cmdOut), nil
+	if totalMatches == 0 {
+		return "No matches found.\n"
+	}
+
+	var (
+		buf   strings.Builder
+		shown int
+		prev  *fswalk.Match
+	)
+	for i := range matches {
+		m := &matches[i]
+		if !m.Context {
+			if shown == maxMatches {
+				break
+			}
+			shown++
+		} else if shown == maxMatches && (prev == nil || prev.Path != m.Path || prev.Line+1 != m.Line) {
+			// only keep trailing context that belongs to the last shown match
+			break
+		}
+
+		if withContext && prev != nil && (prev.Path != m.Path || prev.Line+1 != m.Line) {
+			buf.WriteString("--\n")
+		}
+
+		sep := ":"
+		if m.Context {
+			sep = "-"
+		}
+		if singleFile {
+			fmt.Fprintf(&buf, "%d%s%s\n", m.Line, sep, m.Text)
+		} else {
+			fmt.Fprintf(&buf, "%s%s%d%s%s\n", m.Path, sep, m.Line, sep, m.Text)
+		}
+		prev = m
+	}
+
+	if shown < totalMatches {
+		fmt.Fprintf(&buf, "[showing %s of %s matches in %s files; narrow the search with a more specific pattern, glob or type, or raise max_matches]\n", formatCount(shown), formatCount(totalMatches), formatCount(len(files)))
+	}
+
+	return buf.String()
+}
+
+// formatCount formats n with thousands separators.
+func formatCount(n int) string {
+	s := strconv.Itoa(n)
+	if n < 0 {
+		return "-" + formatCount(-n)
+	}
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
+// defaultCatMaxLines is the maximum number of lines cat will return
+// in a single call when no limit has been configured.
+const defaultCatMaxLines = 2000
+
+const (
+	defaultTreeDepth       = 3
+	defaultTreeTokenBudget = 4000
+	treeMaxEntries         = 50
+)
+
+type TreeArgs struct {
+	Directory   string `json:"directory"`
+	Depth       int    `json:"depth"`
+	TokenBudget int    `json:"-"`
+}
+
+func (a *TreeArgs) Run() (string, error) {
+	dir := a.Directory
+	if dir == "" {
+		dir = "."
+	}
+	depth := a.Depth
+	if depth <= 0 {
+		depth = defaultTreeDepth
+	}
+	budget := a.TokenBudget
+	if budget <= 0 {
+		budget = defaultTreeTokenBudget
+	}
+	return fswalk.Tree(dir, fswalk.TreeOptions{
+		MaxDepth:    depth,
+		MaxEntries:  treeMaxEntries,
+		TokenBudget: budget,
+	})
+}
+
+func (a *TreeArgs) PrettyCommand() string {
+	dir := a.Directory
+	if dir == "" {
+		dir = "."
+	}
+	depth := a.Depth
+	if depth <= 0 {
+		depth = defaultTreeDepth
+	}
+	return fmt.Sprintf("tree -L %d %s", depth, dir)
 }
 
 type CatArgs struct {
-	Filename string `json:"filename"`
+	Filename  string `json:"filename"`
+	StartLine int    `json:"start_line"` // 1-indexed, inclusive; 0 means start of file
+	EndLine   int    `json:"end_line"`   // 1-indexed, inclusive; 0 means end of file
+	MaxLines  int    `json:"-"`
 }
 
 func (a *CatArgs) Run() (string, error) {
 	b, err := os.ReadFile(a.Filename)
-	return string(b), err
+	if err != nil {
+		return "", err
+	}
+
+	lines := strings.Split(string(b), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	total := len(lines)
+
+	start := a.StartLine
+	if start < 1 {
+		start = 1
+	}
+	end := a.EndLine
+	if end < 1 || end > total {
+		end = total
+	}
+	if total > 0 && start > total {
+		return "", fmt.Errorf("start_line %d is past the end of %s (%d lines)", start, a.Filename, total)
+	}
+	if end < start && total > 0 {
+		return "", fmt.Errorf("end_line %d is before start_line %d", end, start)
+	}
+
+	maxLines := a.MaxLines
+	if maxLines <= 0 {
+		maxLines = defaultCatMaxLines
+	}
+
+	var truncated bool
+	if end-start+1 > maxLines {
+		end = start + maxLines - 1
+		truncated = true
+	}
+
+	var buf strings.Builder
+	for i := start; i <= end && i <= total; i++ {
+		fmt.Fprintf(&buf, "%6d\t%s\n", i, lines[i-1])
+	}
+
+	if truncated {
+		fmt.Fprintf(&buf, "[truncated: showing lines %d-%d of %d total lines; use start_line and end_line to read more]\n", start, end, total)
+	} else if start > 1 || end < total {
+		fmt.Fprintf(&buf, "[showing lines %d-%d of %d total lines]\n", start, end, total)
+	}
+
+	return buf.String(), nil
 }
 
 func (a *CatArgs) PrettyCommand() string {
-	return fmt.Sprintf("cat %s", a.Filename)
+	if a.StartLine > 0 || a.EndLine > 0 {
+		end := "$"
+		if a.EndLine > 0 {
+			end = strconv.Itoa(a.EndLine)
+		}
+		return fmt.Sprintf("cat -n %s | sed -n '%d,%sp'", a.Filename, max(a.StartLine, 1), end)
+	}
+	return fmt.Sprintf("cat -n %s", a.Filename)
 }
 
 type ModifyFileArgs struct {
@@ -90,7 +464,11 @@ type ModifyFileArgs struct {
 }
 
 func (a *ModifyFileArgs) Run() (string, error) {
-	err := os.WriteFile(a.Filename, []byte(a.Content), 0644)
+	err := os.MkdirAll(filepath.Dir(a.Filename), 0755)
+	if err != nil {
+		return "", err
+	}
+	err = os.WriteFile(a.Filename, []byte(a.Content), 0644)
 	if err != nil {
 		return "", err
 	}
@@ -107,6 +485,10 @@ type AppendToFileArgs struct {
 }
 
 func (a *AppendToFileArgs) Run() (string, error) {
+	err := os.MkdirAll(filepath.Dir(a.Filename), 0755)
+	if err != nil {
+		return "", err
+	}
 	f, err := os.OpenFile(a.Filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
 	if err != nil {
 		return "", err
@@ -123,6 +505,106 @@ func (a *AppendToFileArgs) PrettyCommand() string {
 	return fmt.Sprintf("cat >> %s <<-EOF\n%s\n\nEOF\n# destination: %s", a.Filename, a.Content, a.Filename)
 }
 
+type MkdirArgs struct {
+	Path string `json:"path"`
+}
+
+func (a *MkdirArgs) Run() (string, error) {
+	err := os.MkdirAll(a.Path, 0755)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Directory %s has been created successfully.", a.Path), nil
+}
+
+func (a *MkdirArgs) PrettyCommand() string {
+	return fmt.Sprintf("mkdir -p %s", a.Path)
+}
+
+type MoveFileArgs struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+}
+
+func (a *MoveFileArgs) Run() (string, error) {
+	if _, err := os.Stat(a.Source); err != nil {
+		return "", err
+	}
+	if _, err := os.Lstat(a.Destination); err == nil {
+		return "", fmt.Errorf("destination %s already exists; delete it first if you want to replace it", a.Destination)
+	}
+	err := os.MkdirAll(filepath.Dir(a.Destination), 0755)
+	if err != nil {
+		return "", err
+	}
+	err = os.Rename(a.Source, a.Destination)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Moved %s to %s successfully.", a.Source, a.Destination), nil
+}
+
+func (a *MoveFileArgs) PrettyCommand() string {
+	return fmt.Sprintf("mv %s %s", a.Source, a.Destination)
+}
+
+type CopyFileArgs struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+}
+
+func (a *CopyFileArgs) Run() (string, error) {
+	info, err := os.Stat(a.Source)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s is a directory; only files can be copied", a.Source)
+	}
+	if _, err := os.Lstat(a.Destination); err == nil {
+		return "", fmt.Errorf("destination %s already exists; delete it first if you want to replace it", a.Destination)
+	}
+
+	content, err := os.ReadFile(a.Source)
+	if err != nil {
+		return "", err
+	}
+	err = os.MkdirAll(filepath.Dir(a.Destination), 0755)
+	if err != nil {
+		return "", err
+	}
+	err = os.WriteFile(a.Destination, content, info.Mode().Perm())
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Copied %s to %s successfully.", a.Source, a.Destination), nil
+}
+
+func (a *CopyFileArgs) PrettyCommand() string {
+	return fmt.Sprintf("cp %s %s", a.Source, a.Destination)
+}
+
+type DeleteFileArgs struct {
+	Filename string `json:"filename"`
+}
+
+// Run removes a file or an empty directory. Non-empty directories are
+// refused so a single approval can't remove a whole tree.
+func (a *DeleteFileArgs) Run() (string, error) {
+	err := os.Remove(a.Filename)
+	if err != nil {
+		if info, statErr := os.Stat(a.Filename); statErr == nil && info.IsDir() {
+			return "", fmt.Errorf("%s is a directory that is not empty; delete its contents first", a.Filename)
+		}
+		return "", err
+	}
+	return fmt.Sprintf("Deleted %s successfully.", a.Filename), nil
+}
+
+func (a *DeleteFileArgs) PrettyCommand() string {
+	return fmt.Sprintf("rm %s", a.Filename)
+}
+
 type ReplaceStringInFileArgs struct {
 	Filename       string
 	OriginalString string
@@ -136,7 +618,20 @@ func (a *ReplaceStringInFileArgs) Run() (string, error) {
 		return "", err
 	}
 
+	if a.Count == 0 {
+		return "", errors.New("count must be non-zero; use a negative count to replace every occurrence")
+	}
+	if a.OriginalString == "" {
+		return "", errors.New("original_string is empty")
+	}
+	if a.OriginalString == a.NewString {
+		return "", errors.New("original_string and new_string are identical; nothing to replace")
+	}
+
 	actualCount, rep := replaceStringCount(string(content), a.OriginalString, a.NewString, a.Count)
+	if actualCount == 0 {
+		return "", notFoundError(string(content), a.OriginalString)
+	}
 	err = os.WriteFile(a.Filename, []byte(rep), 0644)
 	if err != nil {
 		return "", err
@@ -1,10 +1,21 @@
 package interactive
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/psanford/code-buddy/fswalk"
 )
 
 func TestListFilesRegex(t *testing.T) {
+	origRG, origOutput := rgAvailable, cmdCombinedOutput
+	defer func() { rgAvailable, cmdCombinedOutput = origRG, origOutput }()
+
+	rgAvailable = func() bool { return true }
 
 	cmdCombinedOutput = func(name string, arg ...string) ([]byte, error) {
 		return []byte(`LICENSE
@@ -45,3 +56,147 @@ settings.js
 	}
 
 }
+
+func TestCatLineRange(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "lines.txt")
+	err := os.WriteFile(fname, []byte("one\ntwo\nthree\nfour\nfive\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		args   CatArgs
+		expect string
+	}{
+		{
+			name:   "full file",
+			args:   CatArgs{Filename: fname},
+			expect: "     1\tone\n     2\ttwo\n     3\tthree\n     4\tfour\n     5\tfive\n",
+		},
+		{
+			name:   "range",
+			args:   CatArgs{Filename: fname, StartLine: 2, EndLine: 3},
+			expect: "     2\ttwo\n     3\tthree\n[showing lines 2-3 of 5 total lines]\n",
+		},
+		{
+			name:   "truncated",
+			args:   CatArgs{Filename: fname, StartLine: 2, MaxLines: 2},
+			expect: "     2\ttwo\n     3\tthree\n[truncated: showing lines 2-3 of 5 total lines; use start_line and end_line to read more]\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.args.Run()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.expect {
+				t.Fatalf("got %q expected %q", got, tt.expect)
+			}
+		})
+	}
+
+	_, err = (&CatArgs{Filename: fname, StartLine: 10}).Run()
+	if err == nil {
+		t.Fatal("expected error for start_line past end of file")
+	}
+}
+
+func TestFileManagement(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.txt")
+	if err := os.WriteFile(src, []byte("hello\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	copyDst := filepath.Join(dir, "a", "b", "copy.txt")
+	if _, err := (&CopyFileArgs{Source: src, Destination: copyDst}).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := os.ReadFile(copyDst); err != nil || string(b) != "hello\n" {
+		t.Fatalf("copy got %q err %v", b, err)
+	}
+	if _, err := (&CopyFileArgs{Source: src, Destination: copyDst}).Run(); err == nil {
+		t.Fatal("expected copy onto existing file to fail")
+	}
+
+	moveDst := filepath.Join(dir, "c", "moved.txt")
+	if _, err := (&MoveFileArgs{Source: src, Destination: moveDst}).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Fatalf("expected source to be gone after move, stat err: %v", err)
+	}
+
+	if _, err := (&DeleteFileArgs{Filename: filepath.Join(dir, "a")}).Run(); err == nil {
+		t.Fatal("expected deleting a non-empty directory to fail")
+	}
+	if _, err := (&DeleteFileArgs{Filename: moveDst}).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(moveDst); !os.IsNotExist(err) {
+		t.Fatalf("expected file to be deleted, stat err: %v", err)
+	}
+}
+
+func TestRGArgsNoFlagInjection(t *testing.T) {
+	a, err := newRGArgs(map[string]string{
+		"pattern":   "--pre=sh",
+		"directory": "--pre=sh",
+		"glob":      "--pre=sh",
+		"type":      "--pre=sh",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(a.args(), " ")
+	want := "--glob=--pre=sh --type=--pre=sh -e --pre=sh -- --pre=sh"
+	if got != want {
+		t.Errorf("args = %q, want %q", got, want)
+	}
+}
+
+func TestFormatMatches(t *testing.T) {
+	matches := []fswalk.Match{
+		{Path: "a.go", Line: 1, Text: "before", Context: true},
+		{Path: "a.go", Line: 2, Text: "foo"},
+		{Path: "a.go", Line: 3, Text: "after", Context: true},
+		{Path: "a.go", Line: 9, Text: "foo again"},
+		{Path: "b.go", Line: 4, Text: "foo"},
+	}
+
+	got := formatMatches(matches, false, true, 2)
+	expect := `a.go-1-before
+a.go:2:foo
+a.go-3-after
+--
+a.go:9:foo again
+[showing 2 of 3 matches in 2 files; narrow the search with a more specific pattern, glob or type, or raise max_matches]
+`
+	if got != expect {
+		t.Fatalf("got %q expected %q", got, expect)
+	}
+
+	if got := formatMatches(nil, false, false, 10); got != "No matches found.\n" {
+		t.Fatalf("got %q for no matches", got)
+	}
+
+	if got := formatCount(4312); got != "4,312" {
+		t.Fatalf("formatCount got %s", got)
+	}
+}
+
+func TestRunCmdTimeout(t *testing.T) {
+	// The background sleep holds stdout open after sh is killed, like a
+	// test binary started by go test.
+	start := time.Now()
+	_, _, err := runCmdTimeout(time.Second, "sh", "-c", "sleep 30 & sleep 30")
+	if !errors.Is(err, errCmdTimeout) {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("command took %s to time out", elapsed)
+	}
+}
@@ -0,0 +1,176 @@
+package interactive
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/psanford/code-buddy/fswalk"
+	"github.com/psanford/code-buddy/internal/textutil"
+)
+
+// contextFiles is the set of files attached to the conversation. Their
+// contents are re-read before every request so the model always sees the
+// current version.
+type contextFiles struct {
+	paths []string
+}
+
+// add attaches the files matching each pattern. A pattern may be a file,
+// a directory (all non-ignored files below it) or a glob.
+func (c *contextFiles) add(patterns []string) ([]string, error) {
+	var added []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return added, fmt.Errorf("%s: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return added, fmt.Errorf("%s: no matching files", pattern)
+		}
+
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return added, err
+			}
+			files := []string{match}
+			if info.IsDir() {
+				rels, err := fswalk.Files(match)
+				if err != nil {
+					return added, err
+				}
+				files = files[:0]
+				for _, rel := range rels {
+					files = append(files, filepath.Join(match, filepath.FromSlash(rel)))
+				}
+			}
+
+			for _, f := range files {
+				f = filepath.Clean(f)
+				if slices.Contains(c.paths, f) {
+					continue
+				}
+				if isBinaryFile(f) {
+					fmt.Printf("skipping binary file %s\n", f)
+					continue
+				}
+				c.paths = append(c.paths, f)
+				added = append(added, f)
+			}
+		}
+	}
+	return added, nil
+}
+
+// drop detaches files matching any of the patterns, or every file if no
+// patterns are given. A pattern matches a file by path, by glob, or as a
+// directory containing it.
+func (c *contextFiles) drop(patterns []string) []string {
+	if len(patterns) == 0 {
+		dropped := c.paths
+		c.paths = nil
+		return dropped
+	}
+
+	var kept, dropped []string
+	for _, p := range c.paths {
+		if matchesAny(p, patterns) {
+			dropped = append(dropped, p)
+		} else {
+			kept = append(kept, p)
+		}
+	}
+	c.paths = kept
+	return dropped
+}
+
+func matchesAny(path string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = filepath.Clean(pattern)
+		if path == pattern || strings.HasPrefix(path, pattern+string(filepath.Separator)) {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, path); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// read returns the current contents of the attached files. Files that no
+// longer exist are detached and reported.
+func (c *contextFiles) read() ([]FileContent, error) {
+	var (
+		contents []FileContent
+		kept     []string
+	)
+	for _, p := range c.paths {
+		content, err := os.ReadFile(p)
+		if errors.Is(err, fs.ErrNotExist) {
+			fmt.Printf("%s no longer exists, dropping it from the context\n", p)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		kept = append(kept, p)
+		contents = append(contents, FileContent{
+			FileName: p,
+			Content:  string(content),
+		})
+	}
+	c.paths = kept
+	return contents, nil
+}
+
+// list prints the attached files with their sizes.
+func (c *contextFiles) list() {
+	if len(c.paths) == 0 {
+		fmt.Println("no files attached; use /add <file|dir|glob>")
+		return
+	}
+	var total int64
+	for _, p := range c.paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			fmt.Printf("%s (%s)\n", p, err)
+			continue
+		}
+		total += info.Size()
+		fmt.Printf("%s (%s, ~%d tokens)\n", p, textutil.FormatSize(info.Size()), info.Size()/4)
+	}
+	fmt.Printf("%d %s, %s, ~%d tokens\n", len(c.paths), textutil.Plural(len(c.paths), "file", "files"), textutil.FormatSize(total), total/4)
+}
+
+func isBinaryFile(name string) bool {
+	f, err := os.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	buf := make([]byte, 8000)
+	n, _ := f.Read(buf)
+	return bytes.IndexByte(buf[:n], 0) >= 0
+}
+
+// completePath returns paths starting with the last word of line, for
+// tab completion of /add.
+func completePath(line string) []string {
+	fields := strings.Fields(line)
+	var prefix string
+	if len(fields) > 1 && !strings.HasSuffix(line, " ") {
+		prefix = fields[len(fields)-1]
+	}
+	matches, _ := filepath.Glob(prefix + "*")
+	for i, m := range matches {
+		if info, err := os.Stat(m); err == nil && info.IsDir() {
+			matches[i] = m + string(filepath.Separator)
+		}
+	}
+	return matches
+}
@@ -0,0 +1,75 @@
+package interactive
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestContextFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) {
+		t.Helper()
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("main.go", "package main\n")
+	writeFile("util.go", "package main\n\nfunc util() {}\n")
+	writeFile("README.md", "# readme\n")
+	writeFile("pkg/a.go", "package pkg\n")
+	writeFile("pkg/b.go", "package pkg\n")
+	writeFile("pkg/logo.png", "\x89PNG\x00\x00")
+
+	path := func(name string) string { return filepath.Join(dir, filepath.FromSlash(name)) }
+
+	var c contextFiles
+	added, err := c.add([]string{filepath.Join(dir, "*.go"), path("pkg")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{path("main.go"), path("util.go"), path("pkg/a.go"), path("pkg/b.go")}
+	if !slices.Equal(added, want) {
+		t.Fatalf("add = %q, want %q", added, want)
+	}
+
+	// adding again is a no-op
+	added, err = c.add([]string{path("main.go")})
+	if err != nil || len(added) != 0 {
+		t.Fatalf("add duplicate = %q, %v", added, err)
+	}
+
+	if _, err := c.add([]string{path("missing.go")}); err == nil {
+		t.Fatal("add missing file: expected error")
+	}
+
+	dropped := c.drop([]string{path("pkg")})
+	if want := []string{path("pkg/a.go"), path("pkg/b.go")}; !slices.Equal(dropped, want) {
+		t.Fatalf("drop dir = %q, want %q", dropped, want)
+	}
+
+	// files are re-read, and deleted files detached
+	writeFile("main.go", "package main\n\nfunc main() {}\n")
+	if err := os.Remove(path("util.go")); err != nil {
+		t.Fatal(err)
+	}
+	contents, err := c.read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contents) != 1 || contents[0].FileName != path("main.go") || contents[0].Content != "package main\n\nfunc main() {}\n" {
+		t.Fatalf("read = %+v", contents)
+	}
+	if !slices.Equal(c.paths, []string{path("main.go")}) {
+		t.Fatalf("paths after read = %q", c.paths)
+	}
+
+	if dropped := c.drop(nil); len(dropped) != 1 || len(c.paths) != 0 {
+		t.Fatalf("drop all = %q, remaining %q", dropped, c.paths)
+	}
+}
@@ -0,0 +1,97 @@
+package interactive
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/psanford/code-buddy/config"
+)
+
+// customPrompts returns the prompts defined in the config file followed by
+// the template files in the prompts directory. A config entry takes
+// precedence over a file with the same name.
+func (r *Runner) customPrompts() []config.CustomPrompt {
+	prompts := append([]config.CustomPrompt(nil), r.CustomPrompts...)
+	seen := make(map[string]bool)
+	for _, p := range prompts {
+		seen[p.Name] = true
+	}
+
+	dir := config.PromptsDir()
+	if dir == "" {
+		return prompts
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return prompts
+	}
+	var fromFiles []config.CustomPrompt
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		name := strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		fromFiles = append(fromFiles, config.CustomPrompt{
+			Name: name,
+			File: filepath.Join(dir, e.Name()),
+		})
+	}
+	sort.Slice(fromFiles, func(i, j int) bool {
+		return fromFiles[i].Name < fromFiles[j].Name
+	})
+	return append(prompts, fromFiles...)
+}
+
+func (r *Runner) customPromptNames() []string {
+	var names []string
+	for _, p := range r.customPrompts() {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// customPromptTemplate loads and parses the named prompt. Files are read on
+// every call so edits take effect on the next message.
+func (r *Runner) customPromptTemplate(name string) (*template.Template, error) {
+	for _, p := range r.customPrompts() {
+		if p.Name != name {
+			continue
+		}
+		text, err := customPromptText(p)
+		if err != nil {
+			return nil, err
+		}
+		tmpl, err := parsePromptTemplate(text)
+		if err != nil {
+			return nil, fmt.Errorf("parse prompt %s: %w", name, err)
+		}
+		return tmpl, nil
+	}
+	return nil, fmt.Errorf("no custom prompt named %q", name)
+}
+
+func customPromptText(p config.CustomPrompt) (string, error) {
+	if p.File == "" {
+		return p.Prompt, nil
+	}
+	filename := p.File
+	if strings.HasPrefix(filename, "~/") {
+		home, _ := os.UserHomeDir()
+		filename = filepath.Join(home, filename[2:])
+	} else if !filepath.IsAbs(filename) {
+		filename = filepath.Join(filepath.Dir(config.ConfigFilePath()), filename)
+	}
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("read prompt %s: %w", p.Name, err)
+	}
+	return string(content), nil
+}
@@ -0,0 +1,83 @@
+package interactive
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/psanford/code-buddy/config"
+)
+
+func TestCustomPromptTemplates(t *testing.T) {
+	confDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", confDir)
+	t.Setenv("HOME", confDir)
+
+	promptsDir := config.PromptsDir()
+	if err := os.MkdirAll(promptsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	reviewFile := filepath.Join(promptsDir, "review.tmpl")
+	writePrompt := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(reviewFile, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writePrompt(`You review {{.Project}}. pun={{.PunMode}}
+{{template "tools" .}}`)
+	if err := os.WriteFile(filepath.Join(promptsDir, "short.tmpl"), []byte("from file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &Runner{
+		PunMode: true,
+		CustomPrompts: []config.CustomPrompt{
+			{Name: "short", Prompt: "Be brief about {{.Project}}."},
+		},
+	}
+
+	if got, want := strings.Join(r.customPromptNames(), ","), "short,review"; got != want {
+		t.Fatalf("prompt names got %q want %q", got, want)
+	}
+
+	pc := newProjectContext("test-project", nil)
+	r.Prompt = "review"
+	prompt := r.buildSystemPrompt(pc, nil, nil)
+	for _, want := range []string{
+		"You review test-project. pun=true",
+		`<function name="write_file">`,
+		"Today's date is",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+
+	// template files are re-read on every call
+	writePrompt("Updated prompt for {{.Project}}")
+	prompt = r.buildSystemPrompt(pc, nil, nil)
+	if !strings.Contains(prompt, "Updated prompt for test-project") {
+		t.Errorf("prompt not reloaded:\n%s", prompt)
+	}
+
+	// config entries take precedence over files with the same name
+	r.Prompt = "short"
+	prompt = r.buildSystemPrompt(pc, nil, nil)
+	if !strings.Contains(prompt, "Be brief about test-project.") {
+		t.Errorf("expected config prompt:\n%s", prompt)
+	}
+
+	// broken templates fall back to the default prompt
+	writePrompt("{{.NoSuchField}}")
+	r.Prompt = "review"
+	prompt = r.buildSystemPrompt(pc, nil, nil)
+	if !strings.Contains(prompt, "You are a 10x software engineer") {
+		t.Errorf("expected default prompt:\n%s", prompt)
+	}
+
+	if _, err := r.customPromptTemplate("missing"); err == nil {
+		t.Errorf("expected error for unknown prompt")
+	}
+}
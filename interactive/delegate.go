@@ -0,0 +1,112 @@
+package interactive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/psanford/claude/anthropic"
+)
+
+const (
+	defaultDelegateMaxTurns  = 20
+	defaultDelegateMaxTokens = 500000
+)
+
+// DelegateArgs hands a research task to a sub-agent with its own
+// conversation and the read-only tools. Only the sub-agent's final summary
+// is returned, so the files it reads stay out of the parent conversation.
+type DelegateArgs struct {
+	Task string
+
+	runner *Runner
+}
+
+func (a *DelegateArgs) PrettyCommand() string {
+	return fmt.Sprintf("delegate\n%s", a.Task)
+}
+
+func (a *DelegateArgs) Run() (string, error) {
+	return a.RunContext(context.Background())
+}
+
+func (a *DelegateArgs) RunContext(ctx context.Context) (string, error) {
+	if strings.TrimSpace(a.Task) == "" {
+		return "", errors.New("task is empty")
+	}
+	r := a.runner
+
+	b := newSystemPromptBuilder(inferProject(), delegatePromptTemplate)
+	b.Tools = readOnlyTools
+	var err error
+	if b.Instructions, err = findInstructions("."); err != nil {
+		return "", err
+	}
+	system, err := b.Render()
+	if err != nil {
+		return "", err
+	}
+	root, err := toolRoot()
+	if err != nil {
+		return "", err
+	}
+
+	client := r.client
+	if client == nil {
+		client = anthropic.NewClient(r.APIKey, anthropic.WithDebugLogger(r.DebugLogger))
+	}
+	maxTurns := r.DelegateMaxTurns
+	if maxTurns <= 0 {
+		maxTurns = defaultDelegateMaxTurns
+	}
+	maxTokens := r.DelegateMaxTokens
+	if maxTokens <= 0 {
+		maxTokens = defaultDelegateMaxTokens
+	}
+
+	sub := &agent{
+		runner:      r,
+		client:      client,
+		model:       r.apiModel(),
+		system:      system,
+		tools:       readOnlyTools,
+		root:        root,
+		maxTurns:    maxTurns,
+		maxTokens:   maxTokens,
+		log:         &prefixWriter{w: os.Stdout, prefix: "  delegate> "},
+		debugLogger: r.DebugLogger,
+	}
+	summary, err := sub.run(ctx, a.Task)
+	if err != nil {
+		return "", fmt.Errorf("delegate: %w", err)
+	}
+	return strings.TrimSpace(summary), nil
+}
+
+// prefixWriter writes each line with prefix, so the sub-agent's tool calls
+// can be told apart from the parent's.
+type prefixWriter struct {
+	w      io.Writer
+	prefix string
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	text := strings.TrimSuffix(string(b), "\n")
+	text = p.prefix + strings.ReplaceAll(text, "\n", "\n"+p.prefix) + "\n"
+	if _, err := io.WriteString(p.w, text); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+var delegatePromptTemplate = `You are a software engineering assistant doing a research task for another assistant, who is working on a larger task with a user. Use the tools to find the information the task asks for. You can only read files; do not suggest that you have changed anything.
+
+When you have finished, reply with a concise summary of what you found, with nothing after it. The other assistant sees only this summary, not the files you read, so include the file paths, line numbers, names and short code excerpts it needs to act on your findings. Say so if you could not find something.
+
+{{template "context" .}}
+
+{{template "tools" .}}
+`
@@ -0,0 +1,118 @@
+package interactive
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDelegate(t *testing.T) {
+	dir := t.TempDir()
+	chdir(t, dir)
+
+	os.WriteFile("a.txt", []byte("secret detail\n"), 0644)
+
+	client := &fakeClient{
+		replies: []string{
+			fakeFunctionCall("cat", "filename", "a.txt"),
+			fakeFunctionCall("delegate", "task", "recurse"),
+			fakeFunctionCall("write_file", "filename", "a.txt", "content", "bye"),
+			"\nThe answer is in a.txt line 1.\n",
+		},
+	}
+	r := &Runner{client: client, Model: "test-model"}
+
+	cmd, err := r.newCmd("delegate", map[string]string{"task": "find the detail"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := cmd.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "The answer is in a.txt line 1." {
+		t.Errorf("summary = %q", out)
+	}
+	if content, _ := os.ReadFile("a.txt"); string(content) != "secret detail\n" {
+		t.Errorf("a.txt was modified: %q", content)
+	}
+
+	if len(client.requests) != 4 {
+		t.Fatalf("expected 4 requests, got %d", len(client.requests))
+	}
+	first := client.requests[0]
+	if got := first.Messages[0].Content[0].TextContent(); got != "find the detail" {
+		t.Errorf("sub-agent prompt = %q", got)
+	}
+	if strings.Contains(first.System, `<function name="delegate">`) || strings.Contains(first.System, `<function name="write_file">`) {
+		t.Errorf("sub-agent offered tools beyond the read-only set")
+	}
+	if !strings.Contains(first.System, `<function name="cat">`) {
+		t.Errorf("sub-agent not offered cat")
+	}
+	msgs := client.requests[3].Messages
+	for i, want := range map[int]string{2: "secret detail", 4: "tool delegate is not available", 6: "tool write_file is not available"} {
+		if got := msgs[i].Content[0].TextContent(); !strings.Contains(got, want) {
+			t.Errorf("message %d = %q, want %q", i, got, want)
+		}
+	}
+
+	client.replies = []string{
+		fakeFunctionCall("cat", "filename", "a.txt"),
+		fakeFunctionCall("cat", "filename", "a.txt"),
+	}
+	r.DelegateMaxTurns = 2
+	if _, err := cmd.Run(); !errors.Is(err, errAgentTurnBudget) {
+		t.Errorf("expected turn budget error, got %v", err)
+	}
+
+	if _, err := (&DelegateArgs{Task: " ", runner: r}).Run(); err == nil {
+		t.Errorf("expected error for empty task")
+	}
+}
+
+func TestDelegatePromptDocs(t *testing.T) {
+	b := newSystemPromptBuilder("", "")
+	if s := b.String(); !strings.Contains(s, `<function name="delegate">`) || !strings.Contains(s, "Use the delegate tool") {
+		t.Errorf("default prompt doesn't document delegate")
+	}
+	b.Tools = readOnlyTools
+	if s := b.String(); strings.Contains(s, "delegate") {
+		t.Errorf("read-only prompt mentions delegate")
+	}
+}
+
+func TestDelegateConfined(t *testing.T) {
+	dir := t.TempDir()
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	os.WriteFile(outside, []byte("secret\n"), 0644)
+	chdir(t, dir)
+
+	client := &fakeClient{
+		replies: []string{
+			fakeFunctionCall("cat", "filename", outside),
+			fakeFunctionCall("rg", "pattern", "x", "directory", "--pre=sh"),
+			"Nothing found.",
+		},
+	}
+	r := &Runner{client: client, Model: "test-model"}
+	if _, err := (&DelegateArgs{Task: "look around", runner: r}).Run(); err != nil {
+		t.Fatal(err)
+	}
+	msgs := client.requests[2].Messages
+	for i, want := range map[int]string{2: "is outside", 4: "command-line option"} {
+		if got := msgs[i].Content[0].TextContent(); !strings.Contains(got, want) || !strings.Contains(got, "<stdout></stdout>") {
+			t.Errorf("message %d = %q, want %q", i, got, want)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	client.replies = []string{"never sent"}
+	if _, err := (&DelegateArgs{Task: "look around", runner: r}).RunContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled delegate: err = %v", err)
+	}
+}
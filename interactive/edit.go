@@ -0,0 +1,296 @@
+package interactive
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	editSearchMarker  = "<<<<<<< SEARCH"
+	editDividerMarker = "======="
+	editReplaceMarker = ">>>>>>> REPLACE"
+)
+
+type EditFileArgs struct {
+	Filename string     `json:"filename"`
+	Edits    []fileEdit `json:"edits"`
+}
+
+type fileEdit struct {
+	Search  string
+	Replace string
+	// Line is an optional 1-indexed line number used to choose between
+	// multiple occurrences of Search. 0 means the search text must be unique.
+	Line int
+}
+
+func (a *EditFileArgs) PrettyCommand() string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "# edit file %s (%d edits)\n", a.Filename, len(a.Edits))
+	for i, e := range a.Edits {
+		if e.Line > 0 {
+			fmt.Fprintf(&buf, "==== edit %d (near line %d) old ====\n", i+1, e.Line)
+		} else {
+			fmt.Fprintf(&buf, "==== edit %d old ====\n", i+1)
+		}
+		fmt.Fprintf(&buf, "%s\n==== edit %d new ====\n%s\n", e.Search, i+1, e.Replace)
+	}
+	fmt.Fprintf(&buf, "====     ====\n# in %s", a.Filename)
+	return buf.String()
+}
+
+func (a *EditFileArgs) Run() (string, error) {
+	if len(a.Edits) == 0 {
+		return "", errors.New("no edits provided")
+	}
+
+	b, err := os.ReadFile(a.Filename)
+	if err != nil {
+		return "", err
+	}
+	content := string(b)
+
+	type lineShift struct {
+		line  int
+		delta int
+	}
+	var (
+		shifts  []lineShift
+		summary []string
+	)
+
+	for i, e := range a.Edits {
+		// Line anchors refer to the file as it was before this call, so
+		// adjust them for lines added or removed by earlier edits.
+		anchor := e.Line
+		if anchor > 0 {
+			for _, s := range shifts {
+				if s.line < anchor {
+					anchor += s.delta
+				}
+			}
+		}
+
+		idx, err := locateEdit(content, e.Search, anchor)
+		if err != nil {
+			return "", fmt.Errorf("edit %d of %d failed, no changes were written to %s: %w", i+1, len(a.Edits), a.Filename, err)
+		}
+
+		startLine := strings.Count(content[:idx], "\n") + 1
+		oldLines := strings.Count(e.Search, "\n")
+		newLines := strings.Count(e.Replace, "\n")
+
+		content = content[:idx] + e.Replace + content[idx+len(e.Search):]
+
+		shifts = append(shifts, lineShift{line: startLine, delta: newLines - oldLines})
+		summary = append(summary, fmt.Sprintf("edit %d: replaced %d line(s) at line %d with %d line(s)", i+1, oldLines+1, startLine, newLines+1))
+	}
+
+	err = os.WriteFile(a.Filename, []byte(content), 0644)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Applied %d edit(s) to %s.\n%s", len(a.Edits), a.Filename, strings.Join(summary, "\n")), nil
+}
+
+// locateEdit returns the byte offset of search within content. If search
+// occurs more than once, anchor (a 1-indexed line number) selects the
+// occurrence starting closest to it; without an anchor that is an error.
+func locateEdit(content, search string, anchor int) (int, error) {
+	if search == "" {
+		return -1, errors.New("search text is empty")
+	}
+
+	var offsets []int
+	for start := 0; ; {
+		idx := strings.Index(content[start:], search)
+		if idx < 0 {
+			break
+		}
+		offsets = append(offsets, start+idx)
+		start += idx + 1
+	}
+
+	switch {
+	case len(offsets) == 0:
+		return -1, notFoundError(content, search)
+	case len(offsets) == 1:
+		return offsets[0], nil
+	}
+
+	lines := make([]int, len(offsets))
+	for i, off := range offsets {
+		lines[i] = strings.Count(content[:off], "\n") + 1
+	}
+
+	if anchor <= 0 {
+		lineStrs := make([]string, len(lines))
+		for i, l := range lines {
+			lineStrs[i] = strconv.Itoa(l)
+		}
+		return -1, fmt.Errorf("search text is ambiguous: it matches %d locations (lines %s). Include more surrounding context to make it unique, or set the line number of the intended match", len(offsets), strings.Join(lineStrs, ", "))
+	}
+
+	best := 0
+	for i := range lines {
+		if abs(lines[i]-anchor) < abs(lines[best]-anchor) {
+			best = i
+		}
+	}
+	return offsets[best], nil
+}
+
+// notFoundError describes why search was not found in content, including
+// the lines that most closely resemble it so whitespace mismatches are
+// easy to spot.
+func notFoundError(content, search string) error {
+	fileLines := strings.Split(content, "\n")
+	searchLines := strings.Split(search, "\n")
+
+	var buf strings.Builder
+	buf.WriteString("search text was not found in the file")
+
+	// A match that only differs in whitespace is by far the most common mistake.
+	for pos := 0; pos+len(searchLines) <= len(fileLines); pos++ {
+		if linesMatch(fileLines[pos:pos+len(searchLines)], searchLines, matchIgnoreSpace) {
+			fmt.Fprintf(&buf, "\nthe text matches lines %d-%d if whitespace is ignored; the actual lines are:", pos+1, pos+len(searchLines))
+			for i := range searchLines {
+				fmt.Fprintf(&buf, "\n  %d: %q", pos+i+1, fileLines[pos+i])
+			}
+			return errors.New(buf.String())
+		}
+	}
+
+	first := strings.TrimSpace(searchLines[0])
+	for _, l := range searchLines {
+		if strings.TrimSpace(l) != "" {
+			first = strings.TrimSpace(l)
+			break
+		}
+	}
+
+	type candidate struct {
+		line  int
+		score float64
+	}
+	var candidates []candidate
+	for i, l := range fileLines {
+		if score := lineSimilarity(first, strings.TrimSpace(l)); score >= 0.5 {
+			candidates = append(candidates, candidate{line: i, score: score})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+	if len(candidates) > 5 {
+		candidates = candidates[:5]
+	}
+
+	if len(candidates) == 0 {
+		fmt.Fprintf(&buf, "\nno lines resemble %q; read the file again with cat to get its current contents", first)
+		return errors.New(buf.String())
+	}
+
+	fmt.Fprintf(&buf, "\nlines similar to %q:", first)
+	for _, c := range candidates {
+		fmt.Fprintf(&buf, "\n  %d: %q", c.line+1, fileLines[c.line])
+	}
+	return errors.New(buf.String())
+}
+
+// lineSimilarity returns the fraction of whitespace separated tokens
+// shared between a and b.
+func lineSimilarity(a, b string) float64 {
+	if a == "" || b == "" {
+		return 0
+	}
+	if strings.Contains(b, a) || strings.Contains(a, b) {
+		return 1
+	}
+
+	aTokens := strings.Fields(a)
+	bSet := make(map[string]bool)
+	for _, t := range strings.Fields(b) {
+		bSet[t] = true
+	}
+	var shared int
+	for _, t := range aTokens {
+		if bSet[t] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(max(len(aTokens), len(bSet)))
+}
+
+// parseEdits parses one or more edit blocks of the form:
+//
+//	<<<<<<< SEARCH [line]
+//	old text
+//	=======
+//	new text
+//	>>>>>>> REPLACE
+func parseEdits(s string) ([]fileEdit, error) {
+	lines := strings.Split(s, "\n")
+
+	var (
+		edits []fileEdit
+		cur   *fileEdit
+		state int // 0: between blocks, 1: in search, 2: in replace
+
+		searchLines, replaceLines []string
+	)
+
+	for i, line := range lines {
+		switch state {
+		case 0:
+			if !strings.HasPrefix(line, editSearchMarker) {
+				if strings.TrimSpace(line) != "" {
+					return nil, fmt.Errorf("edits line %d: expected %q, got %q", i+1, editSearchMarker, line)
+				}
+				continue
+			}
+			cur = &fileEdit{}
+			if rest := strings.TrimSpace(strings.TrimPrefix(line, editSearchMarker)); rest != "" {
+				n, err := strconv.Atoi(rest)
+				if err != nil || n < 1 {
+					return nil, fmt.Errorf("edits line %d: invalid line number %q after %s", i+1, rest, editSearchMarker)
+				}
+				cur.Line = n
+			}
+			searchLines, replaceLines = nil, nil
+			state = 1
+		case 1:
+			if line == editDividerMarker {
+				state = 2
+				continue
+			}
+			searchLines = append(searchLines, line)
+		case 2:
+			if line == editReplaceMarker {
+				cur.Search = strings.Join(searchLines, "\n")
+				cur.Replace = strings.Join(replaceLines, "\n")
+				edits = append(edits, *cur)
+				state = 0
+				continue
+			}
+			replaceLines = append(replaceLines, line)
+		}
+	}
+
+	switch state {
+	case 1:
+		return nil, fmt.Errorf("edit %d is missing the %q divider", len(edits)+1, editDividerMarker)
+	case 2:
+		return nil, fmt.Errorf("edit %d is missing the %q terminator", len(edits)+1, editReplaceMarker)
+	}
+	if len(edits) == 0 {
+		return nil, fmt.Errorf("no edit blocks found; each edit must start with %q", editSearchMarker)
+	}
+
+	return edits, nil
+}
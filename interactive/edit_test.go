@@ -0,0 +1,126 @@
+package interactive
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEdits(t *testing.T) {
+	input := `<<<<<<< SEARCH
+old one
+=======
+new one
+>>>>>>> REPLACE
+
+<<<<<<< SEARCH 12
+old two
+line two
+=======
+>>>>>>> REPLACE
+`
+	got, err := parseEdits(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []fileEdit{
+		{Search: "old one", Replace: "new one"},
+		{Search: "old two\nline two", Replace: "", Line: 12},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v want %+v", got, want)
+	}
+
+	for _, bad := range []string{
+		"",
+		"<<<<<<< SEARCH\nfoo\n",
+		"<<<<<<< SEARCH\nfoo\n=======\nbar\n",
+		"<<<<<<< SEARCH abc\nfoo\n=======\nbar\n>>>>>>> REPLACE\n",
+	} {
+		if _, err := parseEdits(bad); err == nil {
+			t.Errorf("expected error parsing %q", bad)
+		}
+	}
+}
+
+func TestEditFile(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "f.go")
+	orig := "func a() {\n\treturn\n}\n\nfunc b() {\n\treturn\n}\n"
+	if err := os.WriteFile(fname, []byte(orig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	readFile := func() string {
+		b, err := os.ReadFile(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	// ambiguous without a line anchor
+	_, err := (&EditFileArgs{Filename: fname, Edits: []fileEdit{
+		{Search: "\treturn\n", Replace: "\treturn // a\n"},
+	}}).Run()
+	if err == nil || !strings.Contains(err.Error(), "lines 2, 6") {
+		t.Fatalf("expected ambiguity error listing lines, got %v", err)
+	}
+
+	// a failing second edit must leave the file untouched
+	_, err = (&EditFileArgs{Filename: fname, Edits: []fileEdit{
+		{Search: "func a() {", Replace: "func A() {"},
+		{Search: "func c() {", Replace: "func C() {"},
+	}}).Run()
+	if err == nil || !strings.Contains(err.Error(), "edit 2 of 2") {
+		t.Fatalf("expected edit 2 failure, got %v", err)
+	}
+	if got := readFile(); got != orig {
+		t.Fatalf("file modified by failed edit: %q", got)
+	}
+
+	// whitespace mismatches report the actual lines
+	_, err = (&EditFileArgs{Filename: fname, Edits: []fileEdit{
+		{Search: "func a() {\n    return", Replace: "x"},
+	}}).Run()
+	if err == nil || !strings.Contains(err.Error(), "if whitespace is ignored") || !strings.Contains(err.Error(), `2: "\treturn"`) {
+		t.Fatalf("expected whitespace hint, got %v", err)
+	}
+
+	// the line anchor refers to the original file even after an earlier
+	// edit adds lines above it
+	_, err = (&EditFileArgs{Filename: fname, Edits: []fileEdit{
+		{Search: "func a() {", Replace: "// a does nothing\n// really\nfunc a() {"},
+		{Search: "\treturn\n", Replace: "\treturn // b\n", Line: 6},
+	}}).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "// a does nothing\n// really\nfunc a() {\n\treturn\n}\n\nfunc b() {\n\treturn // b\n}\n"
+	if got := readFile(); got != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+}
+
+func TestReplaceStringInFileNotFound(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "f.txt")
+	if err := os.WriteFile(fname, []byte("hello world\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := (&ReplaceStringInFileArgs{Filename: fname, OriginalString: "hello  world", NewString: "bye", Count: 1}).Run()
+	if err == nil {
+		t.Fatal("expected error when original_string is missing")
+	}
+
+	_, err = (&ReplaceStringInFileArgs{Filename: fname, OriginalString: "hello", NewString: "bye", Count: 0}).Run()
+	if err == nil {
+		t.Fatal("expected error for zero count")
+	}
+
+	_, err = (&ReplaceStringInFileArgs{Filename: fname, OriginalString: "hello", NewString: "hello", Count: 1}).Run()
+	if err == nil || !strings.Contains(err.Error(), "identical") {
+		t.Fatalf("expected identical strings error, got %v", err)
+	}
+}
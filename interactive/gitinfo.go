@@ -0,0 +1,187 @@
+package interactive
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/psanford/code-buddy/internal/textutil"
+)
+
+const (
+	defaultGitStatusMaxLines = 20
+	defaultGitRecentCommits  = 5
+)
+
+// GitInfo describes the state of the git repository for the system prompt.
+type GitInfo struct {
+	Branch        string // empty when HEAD is detached
+	Head          string // abbreviated commit hash, empty before the first commit
+	Upstream      string
+	Ahead, Behind int
+	InProgress    string // rebase, merge, cherry-pick, revert or bisect
+	Dirty         bool
+	Status        []string // git status --porcelain lines, truncated
+	StatusTotal   int
+	RecentCommits []string // "hash subject"
+}
+
+func gitOutput(args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// loadGitInfo collects the state of the repository containing the current
+// directory. It returns nil if the directory is not in a git repository.
+func loadGitInfo(maxStatusLines, recentCommits int) (*GitInfo, error) {
+	if maxStatusLines <= 0 {
+		maxStatusLines = defaultGitStatusMaxLines
+	}
+	if recentCommits <= 0 {
+		recentCommits = defaultGitRecentCommits
+	}
+
+	gitDir, err := gitOutput("rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return nil, nil
+	}
+
+	var info GitInfo
+
+	header, err := gitOutput("status", "--porcelain=v2", "--branch", "--untracked-files=no")
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(header))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[0] != "#" {
+			continue
+		}
+		switch fields[1] {
+		case "branch.oid":
+			if fields[2] != "(initial)" && len(fields[2]) >= 7 {
+				info.Head = fields[2][:7]
+			}
+		case "branch.head":
+			if fields[2] != "(detached)" {
+				info.Branch = fields[2]
+			}
+		case "branch.upstream":
+			info.Upstream = fields[2]
+		case "branch.ab":
+			if len(fields) == 4 {
+				info.Ahead, _ = strconv.Atoi(strings.TrimPrefix(fields[2], "+"))
+				info.Behind, _ = strconv.Atoi(strings.TrimPrefix(fields[3], "-"))
+			}
+		}
+	}
+
+	status, err := gitOutput("status", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(strings.TrimRight(string(status), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		info.StatusTotal++
+		if len(info.Status) < maxStatusLines {
+			info.Status = append(info.Status, line)
+		}
+	}
+	info.Dirty = info.StatusTotal > 0
+
+	info.InProgress = gitOperationInProgress(strings.TrimSpace(string(gitDir)))
+
+	if info.Head != "" {
+		log, err := gitOutput("log", "-n", strconv.Itoa(recentCommits), "--format=%h %s")
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(strings.TrimSpace(string(log)), "\n") {
+			if line != "" {
+				info.RecentCommits = append(info.RecentCommits, line)
+			}
+		}
+	}
+
+	return &info, nil
+}
+
+// gitOperationInProgress reports a rebase, merge or similar operation that
+// has stopped part way, based on the state files git leaves in its directory.
+func gitOperationInProgress(gitDir string) string {
+	checks := []struct {
+		file string
+		op   string
+	}{
+		{"rebase-merge", "rebase"},
+		{"rebase-apply", "rebase"},
+		{"MERGE_HEAD", "merge"},
+		{"CHERRY_PICK_HEAD", "cherry-pick"},
+		{"REVERT_HEAD", "revert"},
+		{"BISECT_LOG", "bisect"},
+	}
+	for _, c := range checks {
+		if _, err := os.Stat(filepath.Join(gitDir, c.file)); err == nil {
+			return c.op
+		}
+	}
+	return ""
+}
+
+func (g *GitInfo) String() string {
+	var buf strings.Builder
+
+	branch := g.Branch
+	if branch == "" {
+		branch = "(detached HEAD)"
+	}
+	fmt.Fprintf(&buf, "branch=%s", branch)
+	if g.Upstream != "" {
+		fmt.Fprintf(&buf, " upstream=%s ahead=%d behind=%d", g.Upstream, g.Ahead, g.Behind)
+	}
+	buf.WriteString("\n")
+
+	if g.Head != "" {
+		fmt.Fprintf(&buf, "head=%s\n", g.Head)
+	} else {
+		buf.WriteString("head=(no commits yet)\n")
+	}
+	if g.InProgress != "" {
+		fmt.Fprintf(&buf, "in_progress=%s\n", g.InProgress)
+	}
+
+	if !g.Dirty {
+		buf.WriteString("working_tree=clean\n")
+	} else {
+		fmt.Fprintf(&buf, "working_tree=dirty (%d changed %s)\n", g.StatusTotal, textutil.Plural(g.StatusTotal, "path", "paths"))
+		buf.WriteString("status:\n")
+		for _, line := range g.Status {
+			buf.WriteString(line + "\n")
+		}
+		if more := g.StatusTotal - len(g.Status); more > 0 {
+			fmt.Fprintf(&buf, "… %d more\n", more)
+		}
+	}
+
+	if len(g.RecentCommits) > 0 {
+		buf.WriteString("recent commits:\n")
+		for _, c := range g.RecentCommits {
+			buf.WriteString(c + "\n")
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,79 @@
+package interactive
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadGitInfo(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir := t.TempDir()
+	chdir(t, dir)
+
+	if info, err := loadGitInfo(0, 0); err != nil || info != nil {
+		t.Fatalf("outside a repository got %v, %v", info, err)
+	}
+
+	git := func(args ...string) {
+		t.Helper()
+		args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %s %s", strings.Join(args, " "), err, out)
+		}
+	}
+	git("init", "-q", "-b", "main")
+
+	info, err := loadGitInfo(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Branch != "main" || info.Head != "" || info.Dirty {
+		t.Fatalf("empty repo: %+v", info)
+	}
+
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		os.WriteFile(name, []byte(name), 0644)
+		git("add", name)
+		git("commit", "-q", "-m", "add "+name)
+	}
+	os.WriteFile("a.txt", []byte("changed"), 0644)
+	os.WriteFile("d.txt", []byte("new"), 0644)
+	os.WriteFile("e.txt", []byte("new"), 0644)
+	os.Mkdir(filepath.Join(".git", "rebase-merge"), 0755)
+
+	info, err = loadGitInfo(2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := info.String()
+	for _, want := range []string{
+		"branch=main\n",
+		"in_progress=rebase\n",
+		"working_tree=dirty (3 changed paths)\nstatus:\n M a.txt\n?? d.txt\n… 1 more\n",
+		" add c.txt\n",
+		" add b.txt\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "add a.txt") {
+		t.Errorf("expected only 2 recent commits:\n%s", got)
+	}
+	if len(info.Head) != 7 {
+		t.Errorf("head=%q", info.Head)
+	}
+
+	b := newSystemPromptBuilder("test-project", "")
+	b.Git = info
+	if prompt := b.String(); !strings.Contains(prompt, "git repository state:\nbranch=main\n") {
+		t.Errorf("git state missing from prompt:\n%s", prompt)
+	}
+}
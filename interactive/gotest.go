@@ -0,0 +1,275 @@
+package interactive
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultGoTestTimeout = 10 * time.Minute
+
+	// limits that keep a noisy test run from flooding the context
+	goTestMaxFailures      = 20
+	goTestMaxOutputLines   = 40
+	goTestMaxBuildErrLines = 60
+)
+
+type GoTestArgs struct {
+	Packages []string      `json:"packages"`
+	RunRegex string        `json:"run"`
+	Timeout  time.Duration `json:"-"`
+}
+
+func (a *GoTestArgs) args() []string {
+	args := []string{"test"}
+	if a.RunRegex != "" {
+		args = append(args, "-run", a.RunRegex)
+	}
+	pkgs := a.Packages
+	if len(pkgs) == 0 {
+		pkgs = []string{"./..."}
+	}
+	return append(args, pkgs...)
+}
+
+func (a *GoTestArgs) PrettyCommand() string {
+	return "go " + strings.Join(a.args(), " ")
+}
+
+func (a *GoTestArgs) Run() (string, error) {
+	timeout := a.Timeout
+	if timeout <= 0 {
+		timeout = defaultGoTestTimeout
+	}
+
+	args := a.args()
+	args = append(args[:1:1], append([]string{"-json"}, args[1:]...)...)
+
+	stdout, stderr, err := runCmdTimeout(timeout, "go", args...)
+	if errors.Is(err, errCmdTimeout) {
+		return "", fmt.Errorf("go test %s; partial results:\n%s", err, summarizeTestEvents(stdout, stderr))
+	}
+	// A non-zero exit just means tests or builds failed; the summary
+	// describes what happened.
+	return summarizeTestEvents(stdout, stderr), nil
+}
+
+type testEvent struct {
+	Action  string
+	Package string
+	Test    string
+	Output  string
+	Elapsed float64
+}
+
+type testResult struct {
+	pkg     string
+	name    string
+	action  string
+	elapsed float64
+	output  []string
+}
+
+var testFailLocationRegex = regexp.MustCompile(`^\s+([\w./-]+\.go:\d+):`)
+
+// summarizeTestEvents turns the output of "go test -json" into a compact
+// report with pass/fail/skip counts, the output of failing tests and the
+// file:line of each failure.
+func summarizeTestEvents(stdout, stderr []byte) string {
+	var (
+		tests     = make(map[string]*testResult)
+		order     []string
+		pkgs      = make(map[string]*testResult)
+		pkgOrder  []string
+		buildErrs []string
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(stdout))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		var ev testEvent
+		if err := json.Unmarshal(line, &ev); err != nil {
+			buildErrs = append(buildErrs, string(line))
+			continue
+		}
+
+		switch ev.Action {
+		case "build-output":
+			buildErrs = append(buildErrs, strings.TrimRight(ev.Output, "\n"))
+			continue
+		case "build-fail":
+			continue
+		}
+
+		if ev.Test == "" {
+			p := pkgs[ev.Package]
+			if p == nil {
+				p = &testResult{pkg: ev.Package}
+				pkgs[ev.Package] = p
+				pkgOrder = append(pkgOrder, ev.Package)
+			}
+			switch ev.Action {
+			case "output":
+				p.output = append(p.output, strings.TrimRight(ev.Output, "\n"))
+			case "pass", "fail", "skip":
+				p.action = ev.Action
+				p.elapsed = ev.Elapsed
+			}
+			continue
+		}
+
+		key := ev.Package + "\x00" + ev.Test
+		t := tests[key]
+		if t == nil {
+			t = &testResult{pkg: ev.Package, name: ev.Test}
+			tests[key] = t
+			order = append(order, key)
+		}
+		switch ev.Action {
+		case "output":
+			t.output = append(t.output, strings.TrimRight(ev.Output, "\n"))
+		case "pass", "fail", "skip":
+			t.action = ev.Action
+			t.elapsed = ev.Elapsed
+		}
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(stderr)), "\n") {
+		if line != "" {
+			buildErrs = append(buildErrs, line)
+		}
+	}
+
+	var passed, failed, skipped int
+	var failures []*testResult
+	for _, key := range order {
+		t := tests[key]
+		switch t.action {
+		case "pass":
+			passed++
+		case "skip":
+			skipped++
+		case "fail":
+			failed++
+			failures = append(failures, t)
+		}
+	}
+
+	var pkgOK, pkgFailed, pkgNoTests int
+	var failedPkgs []*testResult
+	for _, name := range pkgOrder {
+		p := pkgs[name]
+		switch p.action {
+		case "pass":
+			pkgOK++
+		case "skip":
+			pkgNoTests++
+		case "fail":
+			pkgFailed++
+			failedPkgs = append(failedPkgs, p)
+		}
+	}
+
+	var buf strings.Builder
+	status := "PASS"
+	if failed > 0 || pkgFailed > 0 || (len(pkgOrder) == 0 && len(buildErrs) > 0) {
+		status = "FAIL"
+	}
+	fmt.Fprintf(&buf, "%s: %d passed, %d failed, %d skipped", status, passed, failed, skipped)
+	fmt.Fprintf(&buf, " (packages: %d ok, %d failed, %d without tests)\n", pkgOK, pkgFailed, pkgNoTests)
+
+	if len(buildErrs) > 0 {
+		buf.WriteString("\nBuild errors:\n")
+		writeCapped(&buf, buildErrs, goTestMaxBuildErrLines, "")
+	}
+
+	// Failing tests are reported in the order they finished; sort subtests
+	// after their parents so the output reads top down.
+	sort.SliceStable(failures, func(i, j int) bool {
+		if failures[i].pkg != failures[j].pkg {
+			return failures[i].pkg < failures[j].pkg
+		}
+		return failures[i].name < failures[j].name
+	})
+
+	for i, t := range failures {
+		if i == goTestMaxFailures {
+			fmt.Fprintf(&buf, "\n[%d more failing tests not shown]\n", len(failures)-goTestMaxFailures)
+			break
+		}
+		fmt.Fprintf(&buf, "\n--- FAIL: %s (%s, %.2fs)", t.name, t.pkg, t.elapsed)
+		if locs := failureLocations(t.output); len(locs) > 0 {
+			fmt.Fprintf(&buf, " at %s", strings.Join(locs, ", "))
+		}
+		buf.WriteString("\n")
+		writeCapped(&buf, testOutput(t.output), goTestMaxOutputLines, "    ")
+	}
+
+	// A package can fail without a failing test, for example on a panic
+	// in TestMain or an init function.
+	for _, p := range failedPkgs {
+		var hasFailingTest bool
+		for _, t := range failures {
+			if t.pkg == p.pkg {
+				hasFailingTest = true
+				break
+			}
+		}
+		if hasFailingTest {
+			continue
+		}
+		fmt.Fprintf(&buf, "\nFAIL package %s\n", p.pkg)
+		writeCapped(&buf, p.output, goTestMaxOutputLines, "    ")
+	}
+
+	return buf.String()
+}
+
+// testOutput drops the "=== RUN" and "--- FAIL" framing lines that go test
+// adds around each test's output.
+func testOutput(lines []string) []string {
+	var out []string
+	for _, l := range lines {
+		trimmed := strings.TrimSpace(l)
+		if strings.HasPrefix(trimmed, "=== ") || strings.HasPrefix(trimmed, "--- FAIL") || strings.HasPrefix(trimmed, "--- PASS") {
+			continue
+		}
+		out = append(out, l)
+	}
+	return out
+}
+
+func failureLocations(lines []string) []string {
+	var locs []string
+	seen := make(map[string]bool)
+	for _, l := range lines {
+		m := testFailLocationRegex.FindStringSubmatch(l)
+		if m != nil && !seen[m[1]] {
+			seen[m[1]] = true
+			locs = append(locs, m[1])
+		}
+	}
+	return locs
+}
+
+// writeCapped writes at most maxLines lines, keeping the first and last lines
+// since test failures usually put the most useful information at the end.
+func writeCapped(buf *strings.Builder, lines []string, maxLines int, indent string) {
+	if len(lines) > maxLines {
+		head := maxLines / 2
+		tail := maxLines - head
+		omitted := len(lines) - maxLines
+		lines = append(append(lines[:head:head], fmt.Sprintf("[… %d lines omitted …]", omitted)), lines[len(lines)-tail:]...)
+	}
+	for _, l := range lines {
+		buf.WriteString(indent + strings.TrimPrefix(l, "    ") + "\n")
+	}
+}
@@ -0,0 +1,53 @@
+package interactive
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSummarizeTestEvents(t *testing.T) {
+	stdout := `{"Action":"start","Package":"example.com/a"}
+{"Action":"run","Package":"example.com/a","Test":"TestOK"}
+{"Action":"output","Package":"example.com/a","Test":"TestOK","Output":"=== RUN   TestOK\n"}
+{"Action":"pass","Package":"example.com/a","Test":"TestOK","Elapsed":0.01}
+{"Action":"run","Package":"example.com/a","Test":"TestBad"}
+{"Action":"output","Package":"example.com/a","Test":"TestBad","Output":"=== RUN   TestBad\n"}
+{"Action":"output","Package":"example.com/a","Test":"TestBad","Output":"    a_test.go:12: got 1 want 2\n"}
+{"Action":"output","Package":"example.com/a","Test":"TestBad","Output":"--- FAIL: TestBad (0.00s)\n"}
+{"Action":"fail","Package":"example.com/a","Test":"TestBad","Elapsed":0.02}
+{"Action":"run","Package":"example.com/a","Test":"TestSkip"}
+{"Action":"skip","Package":"example.com/a","Test":"TestSkip"}
+{"Action":"output","Package":"example.com/a","Output":"FAIL\n"}
+{"Action":"fail","Package":"example.com/a","Elapsed":0.05}
+{"Action":"skip","Package":"example.com/b"}
+`
+	stderr := "# example.com/c\nc/c.go:3:2: undefined: x\n"
+
+	got := summarizeTestEvents([]byte(stdout), []byte(stderr))
+
+	for _, want := range []string{
+		"FAIL: 1 passed, 1 failed, 1 skipped (packages: 0 ok, 1 failed, 1 without tests)",
+		"Build errors:\n# example.com/c\nc/c.go:3:2: undefined: x\n",
+		"--- FAIL: TestBad (example.com/a, 0.02s) at a_test.go:12\n    a_test.go:12: got 1 want 2\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected summary to contain %q, got:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "=== RUN") {
+		t.Errorf("expected test framing lines to be removed, got:\n%s", got)
+	}
+}
+
+func TestWriteCapped(t *testing.T) {
+	var lines []string
+	for i := 0; i < 10; i++ {
+		lines = append(lines, strings.Repeat("x", i+1))
+	}
+	var buf strings.Builder
+	writeCapped(&buf, lines, 4, "")
+	expect := "x\nxx\n[… 6 lines omitted …]\nxxxxxxxxx\nxxxxxxxxxx\n"
+	if buf.String() != expect {
+		t.Fatalf("got %q expected %q", buf.String(), expect)
+	}
+}
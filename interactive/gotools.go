@@ -0,0 +1,104 @@
+package interactive
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/psanford/code-buddy/gosym"
+)
+
+// maxGoReferences limits the number of references returned by go_references.
+const maxGoReferences = 100
+
+type GoDefinitionArgs struct {
+	Symbol string `json:"symbol"`
+}
+
+func (a *GoDefinitionArgs) PrettyCommand() string {
+	return fmt.Sprintf("# go to definition of %s", a.Symbol)
+}
+
+func (a *GoDefinitionArgs) Run() (string, error) {
+	prog, err := gosym.Load(".")
+	if err != nil {
+		return "", err
+	}
+	obj, err := prog.Lookup(a.Symbol)
+	if err != nil {
+		return "", err
+	}
+	loc, err := prog.Definition(obj)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s: %s\n%s\n", loc, obj, loc.Snippet), nil
+}
+
+type GoReferencesArgs struct {
+	Symbol string `json:"symbol"`
+}
+
+func (a *GoReferencesArgs) PrettyCommand() string {
+	return fmt.Sprintf("# find references to %s", a.Symbol)
+}
+
+func (a *GoReferencesArgs) Run() (string, error) {
+	prog, err := gosym.Load(".")
+	if err != nil {
+		return "", err
+	}
+	obj, err := prog.Lookup(a.Symbol)
+	if err != nil {
+		return "", err
+	}
+
+	refs := prog.References(obj)
+	if len(refs) == 0 {
+		return fmt.Sprintf("No references found to %s.\n", obj), nil
+	}
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "References to %s:\n", obj)
+	for i, ref := range refs {
+		if i == maxGoReferences {
+			fmt.Fprintf(&buf, "[showing %d of %d references]\n", maxGoReferences, len(refs))
+			break
+		}
+		fmt.Fprintf(&buf, "%s: %s\n", ref, ref.Snippet)
+	}
+	return buf.String(), nil
+}
+
+type GoImplementationsArgs struct {
+	Symbol string `json:"symbol"`
+}
+
+func (a *GoImplementationsArgs) PrettyCommand() string {
+	return fmt.Sprintf("# find implementations of %s", a.Symbol)
+}
+
+func (a *GoImplementationsArgs) Run() (string, error) {
+	prog, err := gosym.Load(".")
+	if err != nil {
+		return "", err
+	}
+	obj, err := prog.Lookup(a.Symbol)
+	if err != nil {
+		return "", err
+	}
+
+	impls, err := prog.Implementations(obj)
+	if err != nil {
+		return "", err
+	}
+	if len(impls) == 0 {
+		return fmt.Sprintf("No implementations found for %s.\n", obj), nil
+	}
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "Implementations for %s:\n", obj)
+	for _, impl := range impls {
+		fmt.Fprintf(&buf, "%s: %s\n  %s\n", impl.Location, impl.Name, impl.Location.Snippet)
+	}
+	return buf.String(), nil
+}
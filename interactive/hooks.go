@@ -0,0 +1,209 @@
+package interactive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/psanford/code-buddy/config"
+)
+
+// Hook events.
+const (
+	hookPreToolUse       = "pre-tool-use"
+	hookPostToolUse      = "post-tool-use"
+	hookUserPromptSubmit = "user-prompt-submit"
+	hookStop             = "stop"
+)
+
+const (
+	defaultHookTimeout = 60 * time.Second
+	// hookBlockExitCode is the exit code a hook uses to block an action,
+	// with the reason on stderr.
+	hookBlockExitCode = 2
+)
+
+// hookEvent is the JSON a hook receives on stdin. A hook may change the
+// tool parameters, prompt or tool output by printing a hookResponse.
+type hookEvent struct {
+	Event   string `json:"event"`
+	Session string `json:"session"`
+	Cwd     string `json:"cwd"`
+
+	// tool events
+	Tool   string            `json:"tool,omitempty"`
+	Params map[string]string `json:"params,omitempty"`
+
+	// post-tool-use
+	Output   *string `json:"output,omitempty"`
+	ExitCode *int    `json:"exit_code,omitempty"`
+
+	// user-prompt-submit
+	Prompt string `json:"prompt,omitempty"`
+
+	// stop
+	LastMessage string `json:"last_message,omitempty"`
+	// StopHookActive is set when the model is already continuing because
+	// a stop hook blocked it, so hooks can avoid looping forever.
+	StopHookActive bool `json:"stop_hook_active,omitempty"`
+}
+
+// hookResponse is the JSON a hook may print on stdout when it exits 0.
+// Output that isn't a JSON object is used as Context.
+type hookResponse struct {
+	// Decision "block" stops the action with Reason.
+	Decision string `json:"decision"`
+	Reason   string `json:"reason"`
+	// Context is added to the prompt or tool output the model sees.
+	Context string `json:"context"`
+
+	// replacements for the corresponding event fields
+	Params map[string]string `json:"params"`
+	Prompt *string           `json:"prompt"`
+	Output *string           `json:"output"`
+}
+
+// hookOutcome is the combined result of the hooks run for an event.
+type hookOutcome struct {
+	blocked bool
+	reason  string
+	context []string
+}
+
+func (o hookOutcome) contextText() string {
+	return strings.Join(o.context, "\n")
+}
+
+type hook struct {
+	config.Hook
+	matcher *regexp.Regexp
+}
+
+type hookRunner struct {
+	session string
+	hooks   []hook
+}
+
+func newHookRunner(hooks []config.Hook, session string) (*hookRunner, error) {
+	h := &hookRunner{session: session}
+	for _, c := range hooks {
+		switch c.Event {
+		case hookPreToolUse, hookPostToolUse, hookUserPromptSubmit, hookStop:
+		default:
+			return nil, fmt.Errorf("hook %q: unknown event %q", c.Command, c.Event)
+		}
+		if strings.TrimSpace(c.Command) == "" {
+			return nil, fmt.Errorf("%s hook has no command", c.Event)
+		}
+		var re *regexp.Regexp
+		if c.Matcher != "" {
+			var err error
+			re, err = regexp.Compile("^(?:" + c.Matcher + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("hook %q: bad matcher: %w", c.Command, err)
+			}
+		}
+		h.hooks = append(h.hooks, hook{Hook: c, matcher: re})
+	}
+	return h, nil
+}
+
+// run runs the hooks for ev.Event in config order. Changes a hook makes
+// to the event are applied to ev and seen by the following hooks. The
+// first hook to block stops the rest from running.
+func (h *hookRunner) run(ctx context.Context, ev *hookEvent) hookOutcome {
+	var outcome hookOutcome
+	ev.Session = h.session
+	ev.Cwd, _ = os.Getwd()
+
+	for _, hk := range h.hooks {
+		if hk.Event != ev.Event || (hk.matcher != nil && !hk.matcher.MatchString(ev.Tool)) {
+			continue
+		}
+
+		resp, err := hk.run(ctx, ev)
+		if err != nil {
+			// A broken hook shouldn't end the session.
+			fmt.Printf("%s hook %q err: %s\n", ev.Event, hk.Command, err)
+			continue
+		}
+		if resp.Params != nil {
+			ev.Params = resp.Params
+		}
+		if resp.Prompt != nil {
+			ev.Prompt = *resp.Prompt
+		}
+		if resp.Output != nil {
+			ev.Output = resp.Output
+		}
+		if c := strings.TrimSpace(resp.Context); c != "" {
+			outcome.context = append(outcome.context, c)
+		}
+		if resp.Decision == "block" {
+			outcome.blocked = true
+			outcome.reason = resp.Reason
+			if outcome.reason == "" {
+				outcome.reason = fmt.Sprintf("blocked by %s hook %q", ev.Event, hk.Command)
+			}
+			return outcome
+		}
+	}
+	return outcome
+}
+
+func (hk hook) run(ctx context.Context, ev *hookEvent) (*hookResponse, error) {
+	input, err := json.Marshal(ev)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := defaultHookTimeout
+	if hk.Timeout > 0 {
+		timeout = time.Duration(hk.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", hk.Command)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(), "CODE_BUDDY_HOOK_EVENT="+ev.Event)
+	// A timeout kills everything the hook started, and Run stops waiting
+	// for the output of anything that escaped the process group.
+	killProcessGroup(cmd)
+	cmd.WaitDelay = cmdWaitDelay
+
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == hookBlockExitCode {
+		reason := strings.TrimSpace(stderr.String())
+		if reason == "" {
+			reason = strings.TrimSpace(stdout.String())
+		}
+		return &hookResponse{Decision: "block", Reason: reason}, nil
+	} else if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("timed out after %s", timeout)
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	out := bytes.TrimSpace(stdout.Bytes())
+	var resp hookResponse
+	if len(out) > 0 && out[0] == '{' {
+		if err := json.Unmarshal(out, &resp); err != nil {
+			return nil, fmt.Errorf("parse output: %w", err)
+		}
+		return &resp, nil
+	}
+	resp.Context = string(out)
+	return &resp, nil
+}
@@ -0,0 +1,113 @@
+package interactive
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/psanford/code-buddy/config"
+)
+
+func TestNewHookRunner(t *testing.T) {
+	tests := []struct {
+		name    string
+		hook    config.Hook
+		wantErr bool
+	}{
+		{"valid", config.Hook{Event: hookPreToolUse, Matcher: "write_file|apply_patch", Command: "true"}, false},
+		{"unknown event", config.Hook{Event: "pre-commit", Command: "true"}, true},
+		{"no command", config.Hook{Event: hookStop}, true},
+		{"bad matcher", config.Hook{Event: hookPreToolUse, Matcher: "(", Command: "true"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newHookRunner([]config.Hook{tt.hook}, "sess")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("newHookRunner err = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHookRunner(t *testing.T) {
+	dir := t.TempDir()
+	stdinFile := filepath.Join(dir, "stdin.json")
+
+	h, err := newHookRunner([]config.Hook{
+		// records its input
+		{Event: hookPreToolUse, Command: "cat > " + stdinFile},
+		// only runs for edits
+		{Event: hookPreToolUse, Matcher: "write_file", Command: `echo '{"params": {"filename": "b.go", "content": "x"}, "context": "rewrote filename"}'`},
+		// broken hooks are reported and skipped
+		{Event: hookPreToolUse, Command: "exit 1"},
+		{Event: hookPostToolUse, Command: "echo formatted with gofmt"},
+		{Event: hookPostToolUse, Command: `echo '{"output": "replaced"}'`},
+		{Event: hookUserPromptSubmit, Command: "echo no secrets please >&2; exit 2"},
+		{Event: hookUserPromptSubmit, Command: "echo never runs"},
+	}, "sess")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	ev := &hookEvent{Event: hookPreToolUse, Tool: "cat", Params: map[string]string{"filename": "a.go"}}
+	outcome := h.run(ctx, ev)
+	if outcome.blocked || len(outcome.context) != 0 || ev.Params["filename"] != "a.go" {
+		t.Errorf("cat pre-tool-use: outcome %+v params %v", outcome, ev.Params)
+	}
+
+	var got hookEvent
+	content, err := os.ReadFile(stdinFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(content, &got); err != nil {
+		t.Fatalf("hook stdin %s: %s", content, err)
+	}
+	if got.Event != hookPreToolUse || got.Session != "sess" || got.Tool != "cat" || got.Params["filename"] != "a.go" || got.Cwd == "" {
+		t.Errorf("hook stdin = %s", content)
+	}
+
+	ev = &hookEvent{Event: hookPreToolUse, Tool: "write_file", Params: map[string]string{"filename": "a.go", "content": "x"}}
+	outcome = h.run(ctx, ev)
+	if outcome.blocked || ev.Params["filename"] != "b.go" || !slices.Equal(outcome.context, []string{"rewrote filename"}) {
+		t.Errorf("write_file pre-tool-use: outcome %+v params %v", outcome, ev.Params)
+	}
+
+	output, exitCode := "ok", 0
+	ev = &hookEvent{Event: hookPostToolUse, Tool: "write_file", Output: &output, ExitCode: &exitCode}
+	outcome = h.run(ctx, ev)
+	if *ev.Output != "replaced" || outcome.contextText() != "formatted with gofmt" {
+		t.Errorf("post-tool-use: outcome %+v output %q", outcome, *ev.Output)
+	}
+
+	ev = &hookEvent{Event: hookUserPromptSubmit, Prompt: "here is my password"}
+	outcome = h.run(ctx, ev)
+	if !outcome.blocked || outcome.reason != "no secrets please" || len(outcome.context) != 0 {
+		t.Errorf("user-prompt-submit: outcome %+v", outcome)
+	}
+
+	// no stop hooks configured
+	if outcome := h.run(ctx, &hookEvent{Event: hookStop}); outcome.blocked {
+		t.Errorf("stop: outcome %+v", outcome)
+	}
+}
+
+func TestHookTimeout(t *testing.T) {
+	// The background sleep holds the hook's stdout open; a timeout must
+	// not wait for it.
+	hk := hook{Hook: config.Hook{Event: hookStop, Command: "sleep 30 & sleep 30", Timeout: 1}}
+	start := time.Now()
+	_, err := hk.run(context.Background(), &hookEvent{Event: hookStop})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("hook took %s to time out", elapsed)
+	}
+}
@@ -0,0 +1,216 @@
+package interactive
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/psanford/code-buddy/config"
+	"github.com/psanford/code-buddy/fswalk"
+)
+
+const instructionsFileName = "CODEBUDDY.md"
+
+// InstructionsFile is a CODEBUDDY.md file whose contents are added to the
+// system prompt.
+type InstructionsFile struct {
+	Path    string
+	Scope   string // global, parent, project or subdirectory
+	Content string
+}
+
+// globalInstructionsPath returns the user-wide instructions file, which
+// lives next to the config file.
+func globalInstructionsPath() string {
+	confFile := config.ConfigFilePath()
+	if confFile == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(confFile), instructionsFileName)
+}
+
+// gitRoot returns the top level of the git work tree containing dir, or ""
+// if dir is not in a git repository.
+func gitRoot(dir string) string {
+	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// projectInstructionsPath returns the instructions file for the project:
+// the one at the git root, or in dir if it is not in a git repository.
+func projectInstructionsPath(dir string) string {
+	if root := gitRoot(dir); root != "" {
+		return filepath.Join(root, instructionsFileName)
+	}
+	return filepath.Join(dir, instructionsFileName)
+}
+
+// findInstructions returns the instructions files that apply to dir, from
+// least to most specific: the global file, files in each directory from the
+// git root down to dir, and files in subdirectories of dir.
+func findInstructions(dir string) ([]InstructionsFile, error) {
+	nested, err := nestedInstructions(dir)
+	if err != nil {
+		return nil, err
+	}
+	return loadInstructions(dir, nested)
+}
+
+// nestedInstructions returns the slash separated paths, relative to dir, of
+// the instructions files in subdirectories of dir.
+func nestedInstructions(dir string) ([]string, error) {
+	var nested []string
+	err := fswalk.Walk(dir, func(rel string, d fs.DirEntry) error {
+		if !d.IsDir() && d.Name() == instructionsFileName && path.Dir(rel) != "." {
+			nested = append(nested, rel)
+		}
+		return nil
+	})
+	return nested, err
+}
+
+// loadInstructions reads the instructions files that apply to dir, as
+// described for findInstructions. nested lists the files in subdirectories
+// of dir, which may be stale: ones that no longer exist are skipped.
+func loadInstructions(dir string, nested []string) ([]InstructionsFile, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []InstructionsFile
+	add := func(p, scope string) error {
+		content, err := os.ReadFile(p)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if strings.TrimSpace(string(content)) == "" {
+			return nil
+		}
+		files = append(files, InstructionsFile{
+			Path:    p,
+			Scope:   scope,
+			Content: strings.TrimSpace(string(content)),
+		})
+		return nil
+	}
+
+	if p := globalInstructionsPath(); p != "" {
+		if err := add(p, "global"); err != nil {
+			return nil, err
+		}
+	}
+
+	// Without a git repository only dir itself is considered; walking up
+	// to / would pick up unrelated files.
+	dirs := []string{dir}
+	if root := gitRoot(dir); root != "" {
+		if rel, err := filepath.Rel(root, dir); err == nil && !strings.HasPrefix(rel, "..") {
+			dirs = []string{root}
+			if rel != "." {
+				cur := root
+				for _, part := range strings.Split(rel, string(filepath.Separator)) {
+					cur = filepath.Join(cur, part)
+					dirs = append(dirs, cur)
+				}
+			}
+		}
+	}
+	for i, d := range dirs {
+		scope := "parent"
+		if i == len(dirs)-1 {
+			scope = "project"
+		}
+		if err := add(filepath.Join(d, instructionsFileName), scope); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, rel := range nested {
+		if err := add(filepath.Join(dir, filepath.FromSlash(rel)), "subdirectory"); err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// memoryCommand implements /memory. Without arguments it shows the
+// instructions files in use; "/memory edit [global|project|<path>]" opens
+// one in $EDITOR, creating it if needed.
+func memoryCommand(args string) error {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		files, err := findInstructions(".")
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			fmt.Printf("no %s files found\n", instructionsFileName)
+			fmt.Printf("global: %s\n", globalInstructionsPath())
+			fmt.Printf("project: %s\n", projectInstructionsPath("."))
+			return nil
+		}
+		for _, f := range files {
+			fmt.Printf("== %s (%s)\n%s\n\n", f.Path, f.Scope, f.Content)
+		}
+		return nil
+	}
+
+	if fields[0] != "edit" || len(fields) > 2 {
+		return fmt.Errorf("usage: /memory [edit [global|project|<path>]]")
+	}
+
+	target := projectInstructionsPath(".")
+	if len(fields) == 2 {
+		switch fields[1] {
+		case "global":
+			target = globalInstructionsPath()
+			if target == "" {
+				return fmt.Errorf("no config directory for global %s", instructionsFileName)
+			}
+		case "project":
+		default:
+			target = fields[1]
+			if info, err := os.Stat(target); err == nil && info.IsDir() {
+				target = filepath.Join(target, instructionsFileName)
+			}
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// $EDITOR may include arguments, such as "code --wait".
+	editorArgs := append(strings.Fields(editor), target)
+	cmd := exec.Command(editorArgs[0], editorArgs[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w", editor, err)
+	}
+	fmt.Printf("edited %s; changes apply from the next message\n", target)
+	return nil
+}
@@ -0,0 +1,67 @@
+package interactive
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindInstructions(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	confDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", confDir)
+	t.Setenv("HOME", confDir)
+
+	root := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", root).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %s %s", err, out)
+	}
+
+	writeFile := func(p, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeFile(filepath.Join(confDir, "code-buddy", instructionsFileName), "use tabs\n")
+	writeFile(filepath.Join(root, instructionsFileName), "run make lint\n")
+	writeFile(filepath.Join(root, "svc", instructionsFileName), "use testify\n")
+	writeFile(filepath.Join(root, "svc", "api", instructionsFileName), "never touch generated/\n")
+	writeFile(filepath.Join(root, "svc", "empty", instructionsFileName), "\n")
+	writeFile(filepath.Join(root, "other", instructionsFileName), "unrelated\n")
+
+	files, err := findInstructions(filepath.Join(root, "svc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, f := range files {
+		got = append(got, f.Scope+": "+f.Content)
+	}
+	want := []string{
+		"global: use tabs",
+		"parent: run make lint",
+		"project: use testify",
+		"subdirectory: never touch generated/",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	b := newSystemPromptBuilder("", "")
+	b.Instructions = files
+	prompt := b.String()
+	if !strings.Contains(prompt, "<instructions path=\""+filepath.Join(root, "svc", "api", instructionsFileName)+"\" scope=\"subdirectory\">\nnever touch generated/\n</instructions>") {
+		t.Fatalf("instructions missing from prompt:\n%s", prompt)
+	}
+}
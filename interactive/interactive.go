@@ -3,30 +3,61 @@ package interactive
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/chzyer/readline"
 	"github.com/psanford/claude"
 	"github.com/psanford/claude/anthropic"
+	"github.com/psanford/claude/clientiface"
 	"github.com/psanford/code-buddy/accumulator"
+	"github.com/psanford/code-buddy/commitmsg"
 	"github.com/psanford/code-buddy/config"
+	"github.com/psanford/code-buddy/fswalk"
+	"github.com/psanford/code-buddy/repomap"
 )
 
 type Runner struct {
 	APIKey               string
 	Model                string
 	OverrideSystemPrompt *string
+	Prompt               string // name of the custom prompt template to use
 	DebugLogger          *slog.Logger
 	SystemPromptFiles    []string
 	CustomPrompts        []config.CustomPrompt
+	Commands             []config.SlashCommand
+	Hooks                []config.Hook
+	MCPServers           []config.MCPServer
 	PunMode              bool
+	CatMaxLines          int
+	TreeDepth            int
+	TreeTokenBudget      int
+	RepoMapTokenBudget   int
+	DisableRepoMap       bool
+	PostEditChecks       PostEditChecks
+	DisableGitContext    bool
+	GitStatusMaxLines    int
+	GitRecentCommits     int
+	AutoCommit           bool // commit approved edits after each request
+	AutoCommitBranch     bool // make auto-commits on a code-buddy/<session> branch
+	DisableTools         bool // don't offer the model any tools
+	DelegateMaxTurns     int  // model requests per delegated task
+	DelegateMaxTokens    int  // input plus output tokens per delegated task
+
+	// tools from MCP servers, by namespaced name
+	mcpTools map[string]*mcpTool
+	// client used by delegated sub-agents
+	client clientiface.Client
 }
 
 func (r *Runner) Run(ctx context.Context) error {
@@ -35,57 +66,74 @@ func (r *Runner) Run(ctx context.Context) error {
 		turns        []turnContent
 		multiline    bool
 		systemPrompt string
-		filesContent []FileContent
+		// output of ! commands to send with the next message
+		pendingShell []shellResult
 
-		project = inferProject()
-		stdin   = bufio.NewReader(os.Stdin)
-		client  = anthropic.NewClient(r.APIKey, anthropic.WithDebugLogger(r.DebugLogger))
+		repoMapCache *repomap.Cache
+		stdin        = bufio.NewReader(os.Stdin)
+		client       = anthropic.NewClient(r.APIKey, anthropic.WithDebugLogger(r.DebugLogger))
 	)
 
-	if len(r.SystemPromptFiles) > 0 {
-		for _, filename := range r.SystemPromptFiles {
-			content, err := os.ReadFile(filename)
-			if err != nil {
-				return fmt.Errorf("read %s err: %w", filename, err)
-			}
-			filesContent = append(filesContent, FileContent{
-				FileName: filename,
-				Content:  string(content),
-			})
+	attached := &contextFiles{}
+	for _, filename := range r.SystemPromptFiles {
+		if _, err := os.Stat(filename); err != nil {
+			return fmt.Errorf("read %s err: %w", filename, err)
 		}
+		if _, err := attached.add([]string{filename}); err != nil {
+			return err
+		}
+	}
 
+	if r.Prompt != "" {
+		if _, err := r.customPromptTemplate(r.Prompt); err != nil {
+			return err
+		}
 	}
 
-	rl := readlinePrompt()
-	defer rl.Close()
+	if !r.DisableRepoMap && repomap.IsGoModule(".") {
+		repoMapCache = &repomap.Cache{
+			Root: ".",
+			Opts: repomap.Options{TokenBudget: r.RepoMapTokenBudget},
+		}
+	}
+	pc := newProjectContext(inferProject(), repoMapCache)
 
-OUTER:
-	for {
+	autoCommit := r.AutoCommit
+	if autoCommit && gitRoot(".") == "" {
+		fmt.Println("auto-commit disabled: not in a git repository")
+		autoCommit = false
+	}
+	session := time.Now().Format("20060102-150405")
+	committer := newAutoCommitter(session, autoCommit, r.AutoCommitBranch, &commitmsg.Generator{
+		Client:      client,
+		DebugLogger: r.DebugLogger,
+	})
 
-		if r.OverrideSystemPrompt != nil {
-			systemPrompt = *r.OverrideSystemPrompt
-		} else {
+	hooks, err := newHookRunner(r.Hooks, session)
+	if err != nil {
+		return err
+	}
 
-			promptBuilder := newSystemPromptBuilder(project, "")
-			promptBuilder.PunMode = r.PunMode
-			if strings.HasSuffix(project, ".git") {
-				rgOut, err := exec.Command("rg", "--files").CombinedOutput()
-				if err != nil {
-					return err
-				}
-				rgFileLines := strings.Split(string(rgOut), "\n")
-				promptBuilder.FileCount = len(rgFileLines)
-				if promptBuilder.FileCount > 10 {
-					rgFileLines = rgFileLines[:9]
-				}
+	mcpTools, mcpClients := startMCPServers(ctx, r.MCPServers)
+	r.mcpTools = mcpTools
+	r.client = client
+	for _, c := range mcpClients {
+		defer c.Close()
+	}
 
-				promptBuilder.FirstFilesInProject = rgFileLines
-			}
+	rl := readlinePrompt(r.customPromptNames, func() []string { return attached.paths }, r.slashCommandNames)
+	defer rl.Close()
 
-			promptBuilder.FilesContent = filesContent
+OUTER:
+	for {
 
-			systemPrompt = promptBuilder.String()
+		// Attached files are re-read before every request so the model
+		// sees edits made since they were added.
+		filesContent, err := attached.read()
+		if err != nil {
+			return err
 		}
+		systemPrompt = r.buildSystemPrompt(pc, filesContent, nil)
 
 		var promptLines []string
 		for i, readMoreLines := 0, true; readMoreLines; i++ {
@@ -112,19 +160,46 @@ OUTER:
 			}
 
 			promptLines = append(promptLines, promptLine)
-			if i == 0 && strings.HasPrefix(promptLine, "/") {
+			if i == 0 && (strings.HasPrefix(promptLine, "/") || strings.HasPrefix(promptLine, "!")) {
 				break
 			}
 		}
 
 		userPrompt := strings.TrimSpace(strings.Join(promptLines, "\n"))
+		if strings.HasPrefix(userPrompt, "!") {
+			command := strings.TrimSpace(strings.TrimPrefix(userPrompt, "!"))
+			if command == "" {
+				fmt.Println("usage: !<command>")
+				continue
+			}
+			res := runShellCommand(command, os.Stdout)
+			pc.invalidate()
+			if res.exitCode != 0 {
+				fmt.Printf("exit status %d\n", res.exitCode)
+			}
+
+			fmt.Print("add output to your next message? (Y/n):")
+			os.Stdout.Sync()
+			line, err := stdin.ReadString('\n')
+			if err != nil {
+				return fmt.Errorf("Error reading from stdin: %w\n", err)
+			}
+			if answer := strings.TrimSpace(line); answer != "n" && answer != "N" {
+				pendingShell = append(pendingShell, res)
+			}
+			continue
+		}
+		// tools the model may use for this request, nil for all
+		var requestTools []string
 		if strings.HasPrefix(userPrompt, "/") {
 			cmd := strings.SplitN(userPrompt, " ", 2)[0]
+			customCommand := false
 			switch cmd {
 			case "/help":
-				helpMsg()
+				helpMsg(r.slashCommands())
 			case "/reset":
 				turns = []turnContent{}
+				pendingShell = nil
 			case "/multiline":
 				multiline = !multiline
 				fmt.Printf("multiline=%t\n", multiline)
@@ -141,31 +216,29 @@ OUTER:
 				newSystemPrompt := strings.TrimSpace(strings.TrimPrefix(userPrompt, "/system"))
 				if newSystemPrompt != "" {
 					if newSystemPrompt == "LIST" {
-						for _, customPrompt := range r.CustomPrompts {
-							fmt.Printf("%s\n%s\n\n", customPrompt.Name, customPrompt.Prompt)
+						for _, customPrompt := range r.customPrompts() {
+							if customPrompt.File != "" {
+								fmt.Printf("%s\n(file: %s)\n\n", customPrompt.Name, customPrompt.File)
+							} else {
+								fmt.Printf("%s\n%s\n\n", customPrompt.Name, customPrompt.Prompt)
+							}
 						}
 					} else if newSystemPrompt == "RESET" {
 						r.OverrideSystemPrompt = nil
+						r.Prompt = ""
 						fmt.Println("reset system prompt back to default")
-					} else {
-						var matchCustomPrompt bool
-						for _, customPrompt := range r.CustomPrompts {
-							if customPrompt.Name == newSystemPrompt {
-								matchCustomPrompt = true
-								promptBuilder := newSystemPromptBuilder("", customPrompt.Prompt)
-								promptBuilder.FilesContent = filesContent
-
-								systemPrompt := promptBuilder.String()
-								fmt.Printf("set system_prompt=%s\n", systemPrompt)
-								r.OverrideSystemPrompt = &systemPrompt
-								break
-							}
-						}
-
-						if !matchCustomPrompt {
-							r.OverrideSystemPrompt = &newSystemPrompt
-							fmt.Printf("set system_prompt=%s\n", newSystemPrompt)
+					} else if slices.Contains(r.customPromptNames(), newSystemPrompt) {
+						if _, err := r.customPromptTemplate(newSystemPrompt); err != nil {
+							fmt.Printf("system prompt err: %s\n", err)
+							continue
 						}
+						r.OverrideSystemPrompt = nil
+						r.Prompt = newSystemPrompt
+						systemPrompt := r.buildSystemPrompt(pc, filesContent, nil)
+						fmt.Printf("set system_prompt=%s\n", systemPrompt)
+					} else {
+						r.OverrideSystemPrompt = &newSystemPrompt
+						fmt.Printf("set system_prompt=%s\n", newSystemPrompt)
 					}
 				} else {
 					if r.OverrideSystemPrompt != nil {
@@ -193,48 +266,131 @@ OUTER:
 					fmt.Printf("Tokens: %d\n", lastTurn.InputTokens+lastTurn.OutputTokens)
 				}
 
+			case "/add":
+				args := strings.Fields(strings.TrimPrefix(userPrompt, "/add"))
+				if len(args) == 0 {
+					fmt.Println("usage: /add <file|dir|glob>...")
+					continue
+				}
+				added, err := attached.add(args)
+				for _, f := range added {
+					fmt.Printf("added %s\n", f)
+				}
+				if err != nil {
+					fmt.Printf("add err: %s\n", err)
+				}
+			case "/drop":
+				dropped := attached.drop(strings.Fields(strings.TrimPrefix(userPrompt, "/drop")))
+				if len(dropped) == 0 {
+					fmt.Println("no matching files attached")
+				}
+				for _, f := range dropped {
+					fmt.Printf("dropped %s\n", f)
+				}
+			case "/files":
+				attached.list()
+			case "/tools":
+				switch strings.TrimSpace(strings.TrimPrefix(userPrompt, "/tools")) {
+				case "on":
+					r.DisableTools = false
+				case "off":
+					r.DisableTools = true
+				case "":
+				default:
+					fmt.Println("usage: /tools [on|off]")
+					continue
+				}
+				fmt.Printf("tools=%t\n", !r.DisableTools)
+			case "/mcp":
+				r.listMCPTools()
+			case "/memory":
+				if err := memoryCommand(strings.TrimPrefix(userPrompt, "/memory")); err != nil {
+					fmt.Printf("memory err: %s\n", err)
+				}
+				// edit may have created a new instructions file
+				pc.invalidate()
+			case "/refresh":
+				pc.invalidate()
+			case "/commit":
+				committer.gen.Model = r.apiModel()
+				message := strings.TrimSpace(strings.TrimPrefix(userPrompt, "/commit"))
+				if message != "" {
+					message += "\n"
+				}
+				if err := committer.squash(ctx, message); err != nil {
+					fmt.Printf("commit err: %s\n", err)
+				}
+			case "/git-undo":
+				if err := committer.undo(); err != nil {
+					fmt.Printf("git-undo err: %s\n", err)
+				}
+				pc.invalidate()
 			case "/quit":
 				return nil
 			default:
-				fmt.Println("unknown command")
-				helpMsg()
+				c, ok := r.findSlashCommand(strings.TrimPrefix(cmd, "/"))
+				if !ok {
+					fmt.Println("unknown command")
+					helpMsg(r.slashCommands())
+					break
+				}
+				_, args, _ := strings.Cut(userPrompt, " ")
+				userPrompt = expandSlashCommand(c, args)
+				customCommand = true
+				if len(c.AllowedTools) > 0 {
+					requestTools = c.AllowedTools
+					systemPrompt = r.buildSystemPrompt(pc, filesContent, requestTools)
+				}
 			}
 
+			if !customCommand {
+				continue
+			}
+		}
+
+		submitEv := &hookEvent{Event: hookUserPromptSubmit, Prompt: userPrompt}
+		submit := hooks.run(ctx, submitEv)
+		if submit.blocked {
+			fmt.Printf("prompt blocked by hook: %s\n", submit.reason)
 			continue
 		}
+		userPrompt = submitEv.Prompt
+
+		promptContent, mentionNotes := expandMentions(userPrompt)
+		for _, note := range mentionNotes {
+			fmt.Printf("attached %s\n", note)
+		}
+		if len(pendingShell) > 0 {
+			promptContent = shellContext(pendingShell) + "\n" + promptContent
+			pendingShell = nil
+		}
+		if c := submit.contextText(); c != "" {
+			promptContent += "\n\n<hook_context>\n" + c + "\n</hook_context>"
+		}
 
 		turns = append(turns, turnContent{
 			MessageTurn: claude.MessageTurn{
 				Role: "user",
 				Content: []claude.TurnContent{
-					claude.TextContent(userPrompt),
+					claude.TextContent(promptContent),
 				},
 			},
 		})
 
 		stopSeq := commandPrefix + ",invoke"
 
-		model := r.Model
-		if fullModel := humanModelNameMap[model]; fullModel != "" {
-			model = fullModel
-		}
-
-		maxTokens := 0
-		if model == claude.Claude3Dot7SonnetLatest || model == claude.Claude3Dot7Sonnet2502 {
-			maxTokens = 128000
-		} else if model == claude.Claude3Dot5Sonnet || model == claude.Claude3Dot5Sonnet2410 || model == claude.Claude3Dot5SonnetLatest {
-			maxTokens = 8192
-		}
+		model := r.apiModel()
 
 		req := &claude.MessageRequest{
 			Model:         model,
 			Stream:        true,
 			System:        systemPrompt,
-			MaxTokens:     maxTokens,
+			MaxTokens:     maxTokensForModel(model),
 			StopSequences: []string{stopSeq},
 		}
 
 		moreWork := true
+		stopHookActive := false
 
 		for moreWork {
 			moreWork = false
@@ -273,7 +429,13 @@ OUTER:
 
 			turnContents := make([]claude.TurnContent, 0, len(respMeta.Content))
 
-			var cmd Cmd
+			var (
+				cmd        Cmd
+				cmdErr     error
+				toolName   string
+				toolParams map[string]string
+				replyText  strings.Builder
+			)
 
 			for _, content := range respMeta.Content {
 				blk := content.(*accumulator.ContentBlock)
@@ -288,6 +450,7 @@ OUTER:
 
 				functionCall, contentUntilFirstFunCall, err := parseCommand(blk.Text)
 				turnContents = append(turnContents, claude.TextContent(contentUntilFirstFunCall))
+				replyText.WriteString(contentUntilFirstFunCall)
 
 				if err == io.EOF {
 					continue
@@ -300,41 +463,11 @@ OUTER:
 					paramMap[p.Name] = string(p.Value)
 				}
 
-				switch functionCall.Name {
-				case "list_files":
-					cmd = &ListFilesArgs{
-						Pattern: paramMap["pattern"],
-					}
-				case "rg":
-					cmd = &RGArgs{
-						Pattern:   paramMap["pattern"],
-						Directory: paramMap["directory"],
-					}
-				case "cat":
-					cmd = &CatArgs{
-						Filename: paramMap["filename"],
-					}
-				case "write_file":
-					cmd = &ModifyFileArgs{
-						Filename: paramMap["filename"],
-						Content:  paramMap["content"],
-					}
-				case "append_to_file":
-					cmd = &AppendToFileArgs{
-						Filename: paramMap["filename"],
-						Content:  paramMap["content"],
-					}
-				case "replace_string_in_file":
-					count, _ := strconv.Atoi(paramMap["count"])
-					cmd = &ReplaceStringInFileArgs{
-						Filename:       paramMap["filename"],
-						OriginalString: paramMap["original_string"],
-						NewString:      paramMap["new_string"],
-						Count:          count,
-					}
-				default:
-					return fmt.Errorf("unknown tool %s", blk.ToolName)
+				cmd, cmdErr = r.toolCmd(functionCall.Name, paramMap, requestTools)
+				if errors.Is(cmdErr, errUnknownTool) {
+					return cmdErr
 				}
+				toolName, toolParams = functionCall.Name, paramMap
 			}
 
 			turns = append(turns, turnContent{
@@ -346,7 +479,29 @@ OUTER:
 				OutputTokens: respMeta.Usage.OutputTokens,
 			})
 
-			if cmd != nil {
+			if cmdErr != nil {
+				fmt.Printf("\nInvalid command: %s\n\n", cmdErr)
+				turns = append(turns, functionResultTurn("", cmdErr.Error(), 1))
+				moreWork = true
+			} else if cmd != nil {
+				preEv := &hookEvent{Event: hookPreToolUse, Tool: toolName, Params: toolParams}
+				pre := hooks.run(ctx, preEv)
+				if pre.blocked {
+					fmt.Printf("\n%s\nblocked by hook: %s\n\n", cmd.PrettyCommand(), pre.reason)
+					turns = append(turns, functionResultTurn("", "blocked by hook: "+pre.reason, 1))
+					moreWork = true
+					continue
+				}
+				if !maps.Equal(preEv.Params, toolParams) {
+					cmd, err = r.newCmd(toolName, preEv.Params)
+					if err != nil {
+						fmt.Printf("\nInvalid command from hook: %s\n\n", err)
+						turns = append(turns, functionResultTurn("", err.Error(), 1))
+						moreWork = true
+						continue
+					}
+				}
+
 				fmt.Printf("\nRequest to run command:\n\n%s\n\n", cmd.PrettyCommand())
 				fmt.Print("ok? (y/N):")
 				os.Stdout.Sync()
@@ -371,36 +526,242 @@ OUTER:
 					stderr    string
 					errorCode int
 				)
-				cmdOut, err := cmd.Run()
+				if fm, ok := cmd.(fileModifier); ok {
+					committer.beforeEdit(fm.ModifiedFiles())
+					pc.invalidate()
+				}
+				cmdOut, err := runCmd(ctx, cmd)
 				if err != nil {
 					fmt.Printf("\nCMD ERROR: %s\n", err)
 					stderr = err.Error()
 					errorCode = 1
+				} else if fm, ok := cmd.(fileModifier); ok {
+					committer.track(fm.ModifiedFiles())
+					if report := r.PostEditChecks.Run(fm.ModifiedFiles()); report != "" {
+						cmdOut += "\n\nPost-edit checks:\n" + report
+					}
+				}
+
+				postEv := &hookEvent{Event: hookPostToolUse, Tool: toolName, Params: preEv.Params, Output: &cmdOut, ExitCode: &errorCode}
+				post := hooks.run(ctx, postEv)
+				cmdOut = *postEv.Output
+				if c := strings.TrimSpace(pre.contextText() + "\n" + post.contextText()); c != "" {
+					cmdOut += "\n\nHook context:\n" + c
+				}
+				if post.blocked {
+					cmdOut += "\n\nHook feedback:\n" + post.reason
 				}
 
 				fmt.Printf("\nOutput: %s\n\n", cmdOut)
 
-				toolResp := turnContent{
-					MessageTurn: claude.MessageTurn{
-						Role: "user",
-						Content: []claude.TurnContent{
-							claude.TextContent(fmt.Sprintf(`<function_result>
-<stdout>%s</stdout>
-<stderr>%s</stderr>
-<exit_code>%d</exit_code>
-</function_result>`, cmdOut, stderr, errorCode)),
+				turns = append(turns, functionResultTurn(cmdOut, stderr, errorCode))
+				moreWork = true
+			}
+
+			if !moreWork {
+				// The model has finished; a stop hook can send it back to
+				// work, for example until the tests pass.
+				stop := hooks.run(ctx, &hookEvent{Event: hookStop, LastMessage: replyText.String(), StopHookActive: stopHookActive})
+				if stop.blocked {
+					fmt.Printf("\nstop hook: %s\n\n", stop.reason)
+					turns = append(turns, turnContent{
+						MessageTurn: claude.MessageTurn{
+							Role: "user",
+							Content: []claude.TurnContent{
+								claude.TextContent("<hook_feedback>\n" + stop.reason + "\n</hook_feedback>"),
+							},
 						},
-					},
+					})
+					moreWork = true
+					stopHookActive = true
 				}
-
-				turns = append(turns, toolResp)
-				moreWork = true
 			}
 		}
+
+		committer.gen.Model = model
+		committer.afterRequest(ctx, userPrompt)
 	}
 	return nil
 }
 
+// apiModel returns the full name of the selected model.
+func (r *Runner) apiModel() string {
+	return FullModelName(r.Model)
+}
+
+func maxTokensForModel(model string) int {
+	if model == claude.Claude3Dot7SonnetLatest || model == claude.Claude3Dot7Sonnet2502 {
+		return 128000
+	} else if model == claude.Claude3Dot5Sonnet || model == claude.Claude3Dot5Sonnet2410 || model == claude.Claude3Dot5SonnetLatest {
+		return 8192
+	}
+	return 0
+}
+
+// FullModelName expands short model names such as "sonnet" to the full
+// model name.
+func FullModelName(model string) string {
+	if fullModel := humanModelNameMap[model]; fullModel != "" {
+		return fullModel
+	}
+	return model
+}
+
+const (
+	defaultPromptTreeDepth       = 2
+	defaultPromptTreeTokenBudget = 1500
+	promptTreeMaxEntries         = 20
+)
+
+// buildSystemPrompt renders the system prompt for the next request. It is
+// called before every message so instructions files, git state and custom
+// prompt templates are always current; the parts that need a walk of the
+// project come from pc and are only rebuilt when it is stale.
+func (r *Runner) buildSystemPrompt(pc *projectContext, filesContent []FileContent, tools []string) string {
+	if r.OverrideSystemPrompt != nil {
+		return *r.OverrideSystemPrompt
+	}
+
+	r.refreshProjectContext(pc)
+
+	promptBuilder := newSystemPromptBuilder(pc.project, "")
+	promptBuilder.PunMode = r.PunMode
+	promptBuilder.DisableTools = r.DisableTools
+	promptBuilder.PostEditChecks = r.PostEditChecks
+	promptBuilder.Tools = tools
+	promptBuilder.MCPTools = r.mcpToolDocs()
+	promptBuilder.FileCount = pc.fileCount
+	promptBuilder.FirstFilesInProject = pc.firstFiles
+	promptBuilder.ProjectTree = pc.tree
+	promptBuilder.RepoMap = pc.repoMapText
+
+	if !r.DisableGitContext {
+		gitInfo, err := loadGitInfo(r.GitStatusMaxLines, r.GitRecentCommits)
+		if err != nil {
+			fmt.Printf("git context err: %s\n", err)
+		}
+		promptBuilder.Git = gitInfo
+	}
+
+	promptBuilder.FilesContent = filesContent
+
+	// Re-read every turn so edits made with /memory or by the
+	// assistant take effect immediately.
+	instructions, err := loadInstructions(".", pc.nestedInstructions)
+	if err != nil {
+		fmt.Printf("instructions err: %s\n", err)
+	}
+	promptBuilder.Instructions = instructions
+
+	if r.Prompt != "" {
+		// A broken template shouldn't end the session; report it and
+		// fall back to the default prompt until it is fixed.
+		var s string
+		tmpl, err := r.customPromptTemplate(r.Prompt)
+		if err == nil {
+			defaultTmpl := promptBuilder.Template
+			promptBuilder.Template = tmpl
+			s, err = promptBuilder.Render()
+			if err == nil {
+				return s
+			}
+			promptBuilder.Template = defaultTmpl
+		}
+		fmt.Printf("custom prompt %s err: %s; using the default prompt\n", r.Prompt, err)
+	}
+
+	return promptBuilder.String()
+}
+
+// projectTree renders the directory tree included in the system prompt.
+func (r *Runner) projectTree() (string, error) {
+	depth := r.TreeDepth
+	if depth <= 0 {
+		depth = defaultPromptTreeDepth
+	}
+	budget := r.TreeTokenBudget
+	if budget <= 0 {
+		budget = defaultPromptTreeTokenBudget
+	}
+	return fswalk.Tree(".", fswalk.TreeOptions{
+		MaxDepth:    depth,
+		MaxEntries:  promptTreeMaxEntries,
+		TokenBudget: budget,
+	})
+}
+
+func functionResultTurn(stdout, stderr string, exitCode int) turnContent {
+	return turnContent{
+		MessageTurn: claude.MessageTurn{
+			Role: "user",
+			Content: []claude.TurnContent{
+				claude.TextContent(fmt.Sprintf(`<function_result>
+<stdout>%s</stdout>
+<stderr>%s</stderr>
+<exit_code>%d</exit_code>
+</function_result>`, stdout, stderr, exitCode)),
+			},
+		},
+	}
+}
+
+// intParam parses an optional integer function parameter. Missing or
+// empty parameters return 0.
+func intParam(params map[string]string, name string) (int, error) {
+	v := strings.TrimSpace(params[name])
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s parameter %q: must be an integer", name, v)
+	}
+	return n, nil
+}
+
+// boolParam parses an optional boolean function parameter. Missing or
+// empty parameters return false.
+func boolParam(params map[string]string, name string) (bool, error) {
+	v := strings.TrimSpace(params[name])
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s parameter %q: must be true or false", name, v)
+	}
+	return b, nil
+}
+
+// listParam splits a whitespace separated function parameter.
+func listParam(params map[string]string, name string) []string {
+	return strings.Fields(params[name])
+}
+
+func newRGArgs(params map[string]string) (*RGArgs, error) {
+	a := &RGArgs{
+		Pattern:   params["pattern"],
+		Directory: strings.TrimSpace(params["directory"]),
+		Globs:     listParam(params, "glob"),
+		Types:     listParam(params, "type"),
+	}
+
+	var err error
+	if a.Context, err = intParam(params, "context"); err != nil {
+		return a, err
+	}
+	if a.MaxMatches, err = intParam(params, "max_matches"); err != nil {
+		return a, err
+	}
+	if a.IgnoreCase, err = boolParam(params, "ignore_case"); err != nil {
+		return a, err
+	}
+	if a.FixedStrings, err = boolParam(params, "fixed_strings"); err != nil {
+		return a, err
+	}
+	return a, nil
+}
+
 type InputSchema struct {
 	Properties map[string]struct {
 		Description string `json:"description"`
@@ -442,7 +803,7 @@ func readUserPrompt(stdin *bufio.Reader) (string, error) {
 	}
 }
 
-func helpMsg() {
+func helpMsg(commands []config.SlashCommand) {
 	fmt.Println(`help
 /help							- show this help message
 /reset						- clear all history and start again
@@ -451,10 +812,33 @@ func helpMsg() {
 /system <prompt>	- get/set system prompt (RESET to reset, LIST to list custom prompts, <custom_prompt_name> to use custom prompt, <prompt> to use prompt text)
 /history					- show full conversation history
 /info             - show summary info about conversation
-/quit							- exit program`)
+/add <file|dir|glob>	- attach files to the context; they are re-read before every request
+/drop [file|glob]	- detach files, or all files without an argument
+/files						- list attached files
+/tools [on|off]		- get/set whether the model can use tools
+/mcp							- list tools from MCP servers
+/memory [edit [global|project|<path>]] - show or edit CODEBUDDY.md instruction files
+/refresh					- rescan the project for the system prompt after changing files outside code-buddy
+/commit [message]	- commit the files the assistant changed, squashing this session's auto-commits into one
+/git-undo					- remove or revert the most recent commit made by this session
+/quit							- exit program
+
+Mention a file or directory as @path in a prompt to include its contents or a listing with that message.
+Start a line with ! to run a shell command; its output can be sent with your next message.`)
+
+	if len(commands) > 0 {
+		fmt.Println("\ncustom commands:")
+		for _, c := range commands {
+			desc := c.Description
+			if desc == "" {
+				desc = firstLine(c.Prompt)
+			}
+			fmt.Printf("/%s\t- %s\n", c.Name, desc)
+		}
+	}
 }
 
-func readlinePrompt() *readline.Instance {
+func readlinePrompt(customPromptNames, attachedFiles, slashCommandNames func() []string) *readline.Instance {
 	cacheDirRoot, _ := os.UserCacheDir()
 	if cacheDirRoot == "" {
 		cacheDirRoot = filepath.Join(os.Getenv("HOME"), ".cache")
@@ -474,16 +858,46 @@ func readlinePrompt() *readline.Instance {
 				return []string{"sonnet", "haiku", "opus"}
 			}),
 		),
-		readline.PcItem("/system"),
+		readline.PcItem("/system",
+			readline.PcItemDynamic(func(line string) []string {
+				return append([]string{"LIST", "RESET"}, customPromptNames()...)
+			}),
+		),
 		readline.PcItem("/history"),
 		readline.PcItem("/info"),
+		readline.PcItem("/add",
+			readline.PcItemDynamic(completePath),
+		),
+		readline.PcItem("/drop",
+			readline.PcItemDynamic(func(line string) []string {
+				return attachedFiles()
+			}),
+		),
+		readline.PcItem("/files"),
+		readline.PcItem("/tools",
+			readline.PcItem("on"),
+			readline.PcItem("off"),
+		),
+		readline.PcItem("/mcp"),
+		readline.PcItem("/memory",
+			readline.PcItem("edit",
+				readline.PcItem("global"),
+				readline.PcItem("project"),
+			),
+		),
+		readline.PcItem("/refresh"),
+		readline.PcItem("/commit"),
+		readline.PcItem("/git-undo"),
 		readline.PcItem("/quit"),
+		readline.PcItemDynamic(func(line string) []string {
+			return slashCommandNames()
+		}),
 	)
 
 	l, err := readline.NewEx(&readline.Config{
 		Prompt:            "prompt> ",
 		HistoryFile:       historyFile,
-		AutoComplete:      completer,
+		AutoComplete:      &mentionCompleter{completer},
 		InterruptPrompt:   "^C",
 		EOFPrompt:         "/quit",
 		HistorySearchFold: true,
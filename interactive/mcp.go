@@ -0,0 +1,225 @@
+package interactive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/psanford/code-buddy/config"
+	"github.com/psanford/code-buddy/internal/textutil"
+	"github.com/psanford/code-buddy/mcp"
+)
+
+const (
+	mcpToolPrefix          = "mcp__"
+	defaultMCPStartTimeout = 30 * time.Second
+	defaultMCPCallTimeout  = 2 * time.Minute
+)
+
+var mcpServerNameRe = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// mcpTool is a tool provided by an MCP server.
+type mcpTool struct {
+	name    string // namespaced name offered to the model
+	server  string
+	client  *mcp.Client
+	tool    mcp.Tool
+	timeout time.Duration
+}
+
+func mcpToolName(server, tool string) string {
+	return mcpToolPrefix + server + "__" + tool
+}
+
+// startMCPServers starts the configured servers and lists their tools. A
+// server that fails to start is reported and skipped so the session can
+// continue without it.
+func startMCPServers(ctx context.Context, servers []config.MCPServer) (map[string]*mcpTool, []*mcp.Client) {
+	var (
+		tools   = make(map[string]*mcpTool)
+		clients []*mcp.Client
+	)
+	for _, s := range servers {
+		if !mcpServerNameRe.MatchString(s.Name) {
+			fmt.Printf("mcp server %q: name must only contain letters, digits, _ and -\n", s.Name)
+			continue
+		}
+
+		var env []string
+		for k, v := range s.Env {
+			env = append(env, k+"="+v)
+		}
+		startCtx, cancel := context.WithTimeout(ctx, defaultMCPStartTimeout)
+		client, err := mcp.Start(startCtx, mcp.ServerParams{Command: s.Command, Args: s.Args, Env: env})
+		if err != nil {
+			cancel()
+			fmt.Printf("mcp server %s err: %s\n", s.Name, err)
+			continue
+		}
+		serverTools, err := client.ListTools(startCtx)
+		cancel()
+		if err != nil {
+			fmt.Printf("mcp server %s: list tools err: %s\n", s.Name, err)
+			client.Close()
+			continue
+		}
+		clients = append(clients, client)
+
+		timeout := defaultMCPCallTimeout
+		if s.Timeout > 0 {
+			timeout = time.Duration(s.Timeout) * time.Second
+		}
+		for _, t := range serverTools {
+			name := mcpToolName(s.Name, t.Name)
+			tools[name] = &mcpTool{
+				name:    name,
+				server:  s.Name,
+				client:  client,
+				tool:    t,
+				timeout: timeout,
+			}
+		}
+		fmt.Printf("mcp server %s: %d %s\n", s.Name, len(serverTools), textutil.Plural(len(serverTools), "tool", "tools"))
+	}
+	return tools, clients
+}
+
+// mcpToolDocs describes the MCP tools for the system prompt.
+func (r *Runner) mcpToolDocs() []ToolDoc {
+	var docs []ToolDoc
+	for _, t := range r.mcpTools {
+		doc := ToolDoc{Name: t.name}
+		var desc strings.Builder
+		desc.WriteString(strings.TrimSpace(t.tool.Description))
+
+		schema := t.tool.InputSchema
+		names := make([]string, 0, len(schema.Properties))
+		for name := range schema.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) > 0 {
+			desc.WriteString("\nParameters:")
+		}
+		for _, name := range names {
+			doc.Params = append(doc.Params, name)
+			prop := schema.Properties[name]
+			fmt.Fprintf(&desc, "\n- %s (%s", name, prop.Type)
+			for _, req := range schema.Required {
+				if req == name {
+					desc.WriteString(", required")
+				}
+			}
+			desc.WriteString(")")
+			if prop.Description != "" {
+				desc.WriteString(": " + prop.Description)
+			}
+		}
+		if len(names) > 0 {
+			desc.WriteString("\nObject and array parameters are JSON.")
+		}
+		doc.Description = desc.String()
+		docs = append(docs, doc)
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		return docs[i].Name < docs[j].Name
+	})
+	return docs
+}
+
+// listMCPTools implements /mcp.
+func (r *Runner) listMCPTools() {
+	if len(r.mcpTools) == 0 {
+		fmt.Println("no mcp tools; add [[mcp_server]] entries to the config file")
+		return
+	}
+	for _, doc := range r.mcpToolDocs() {
+		fmt.Printf("%s\n  %s\n", doc.Name, firstLine(r.mcpTools[doc.Name].tool.Description))
+	}
+}
+
+// MCPToolArgs calls a tool on an MCP server.
+type MCPToolArgs struct {
+	tool      *mcpTool
+	Arguments map[string]any
+}
+
+func newMCPToolArgs(t *mcpTool, params map[string]string) (*MCPToolArgs, error) {
+	args, err := mcpArguments(t.tool.InputSchema, params)
+	if err != nil {
+		return nil, err
+	}
+	return &MCPToolArgs{tool: t, Arguments: args}, nil
+}
+
+func (a *MCPToolArgs) PrettyCommand() string {
+	args, _ := json.MarshalIndent(a.Arguments, "", "  ")
+	return fmt.Sprintf("%s (mcp server %s)\n%s", a.tool.tool.Name, a.tool.server, args)
+}
+
+func (a *MCPToolArgs) Run() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), a.tool.timeout)
+	defer cancel()
+	res, err := a.tool.client.CallTool(ctx, a.tool.tool.Name, a.Arguments)
+	if err != nil {
+		return "", err
+	}
+	text := mcpResultText(res)
+	if res.IsError {
+		return "", errors.New(text)
+	}
+	return text, nil
+}
+
+// mcpArguments converts the string parameters of a function call to the
+// JSON types the tool's input schema expects.
+func mcpArguments(schema mcp.Schema, params map[string]string) (map[string]any, error) {
+	args := make(map[string]any, len(params))
+	for name, value := range params {
+		var typ string
+		if prop := schema.Properties[name]; prop != nil {
+			typ = prop.Type
+		}
+		switch typ {
+		case "string":
+			args[name] = value
+		case "integer", "number", "boolean", "object", "array", "null":
+			var v any
+			if err := json.Unmarshal([]byte(strings.TrimSpace(value)), &v); err != nil {
+				return nil, fmt.Errorf("parameter %s: expected %s: %w", name, typ, err)
+			}
+			args[name] = v
+		default:
+			// no type given: use JSON values when they parse
+			var v any
+			if json.Unmarshal([]byte(strings.TrimSpace(value)), &v) == nil {
+				args[name] = v
+			} else {
+				args[name] = value
+			}
+		}
+	}
+	for _, req := range schema.Required {
+		if _, ok := args[req]; !ok {
+			return nil, fmt.Errorf("missing required parameter %s", req)
+		}
+	}
+	return args, nil
+}
+
+func mcpResultText(res *mcp.CallToolResult) string {
+	var parts []string
+	for _, c := range res.Content {
+		if c.Type == "text" {
+			parts = append(parts, c.Text)
+		} else {
+			parts = append(parts, fmt.Sprintf("[%s content (%s) omitted]", c.Type, c.MimeType))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
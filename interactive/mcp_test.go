@@ -0,0 +1,109 @@
+package interactive
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/psanford/code-buddy/mcp"
+)
+
+func TestMCPArguments(t *testing.T) {
+	schema := mcp.Schema{
+		Type: "object",
+		Properties: map[string]*mcp.Schema{
+			"query":  {Type: "string"},
+			"limit":  {Type: "integer"},
+			"open":   {Type: "boolean"},
+			"labels": {Type: "array"},
+			"extra":  {},
+		},
+		Required: []string{"query"},
+	}
+
+	tests := []struct {
+		name    string
+		params  map[string]string
+		want    map[string]any
+		wantErr bool
+	}{
+		{
+			name:   "typed values",
+			params: map[string]string{"query": "42", "limit": " 10\n", "open": "true", "labels": `["bug", "p1"]`},
+			want:   map[string]any{"query": "42", "limit": float64(10), "open": true, "labels": []any{"bug", "p1"}},
+		},
+		{
+			name:   "untyped values",
+			params: map[string]string{"query": "q", "extra": "not json"},
+			want:   map[string]any{"query": "q", "extra": "not json"},
+		},
+		{
+			name:    "bad integer",
+			params:  map[string]string{"query": "q", "limit": "ten"},
+			wantErr: true,
+		},
+		{
+			name:    "missing required",
+			params:  map[string]string{"limit": "1"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mcpArguments(schema, tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mcpArguments = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMCPToolsInPrompt(t *testing.T) {
+	r := &Runner{
+		mcpTools: map[string]*mcpTool{
+			"mcp__tracker__get_issue": {
+				name:   "mcp__tracker__get_issue",
+				server: "tracker",
+				tool: mcp.Tool{
+					Name:        "get_issue",
+					Description: "Fetch an issue.",
+					InputSchema: mcp.Schema{
+						Type: "object",
+						Properties: map[string]*mcp.Schema{
+							"id": {Type: "integer", Description: "issue number"},
+						},
+						Required: []string{"id"},
+					},
+				},
+			},
+		},
+	}
+
+	b := newSystemPromptBuilder("test-project", "")
+	b.MCPTools = r.mcpToolDocs()
+	prompt := b.String()
+	for _, s := range []string{
+		"<function name=\"mcp__tracker__get_issue\">\n<parameter name=\"id\"/>\n<description>Fetch an issue.\nParameters:\n- id (integer, required): issue number",
+	} {
+		if !strings.Contains(prompt, s) {
+			t.Errorf("expected prompt to contain %q", s)
+		}
+	}
+
+	b.Tools = readOnlyTools
+	if strings.Contains(b.String(), "mcp__tracker__get_issue") {
+		t.Errorf("mcp tool offered with a restricted tool list")
+	}
+
+	cmd, err := r.newCmd("mcp__tracker__get_issue", map[string]string{"id": "7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args := cmd.(*MCPToolArgs).Arguments; args["id"] != float64(7) {
+		t.Errorf("arguments = %v", args)
+	}
+}
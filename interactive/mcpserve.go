@@ -0,0 +1,230 @@
+package interactive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/psanford/code-buddy/mcp"
+)
+
+// mcpServeTools are the tools exposed by "code-buddy mcp-serve", with their
+// required parameters. Edit tools can preview their changes.
+var mcpServeTools = []struct {
+	name     string
+	required []string
+	edit     bool
+}{
+	{"cat", []string{"filename"}, false},
+	{"list_files", nil, false},
+	{"rg", []string{"pattern"}, false},
+	{"write_file", []string{"filename", "content"}, true},
+	{"append_to_file", []string{"filename", "content"}, true},
+	{"replace_string_in_file", []string{"filename", "original_string", "new_string"}, true},
+	{"edit_file", []string{"filename", "edits"}, true},
+	{"apply_patch", []string{"patch"}, true},
+}
+
+// mcpParamTypes gives the JSON type of parameters that aren't strings.
+var mcpParamTypes = map[string]string{
+	"start_line":    "integer",
+	"end_line":      "integer",
+	"count":         "integer",
+	"context":       "integer",
+	"max_matches":   "integer",
+	"ignore_case":   "boolean",
+	"fixed_strings": "boolean",
+}
+
+var functionDocRe = regexp.MustCompile(`(?s)<function name="([^"]+)">\n((?:<parameter name="[^"]+"/>\n)*)<description>(.*?)</description>`)
+var parameterDocRe = regexp.MustCompile(`<parameter name="([^"]+)"/>`)
+
+// builtinToolDocs extracts the tool descriptions from the system prompt
+// template, so MCP clients see the same documentation as our own model.
+func builtinToolDocs() ([]ToolDoc, error) {
+	b := newSystemPromptBuilder("", "")
+	var buf strings.Builder
+	if err := b.Template.ExecuteTemplate(&buf, "tools", b); err != nil {
+		return nil, err
+	}
+	var docs []ToolDoc
+	for _, m := range functionDocRe.FindAllStringSubmatch(buf.String(), -1) {
+		doc := ToolDoc{Name: m[1], Description: strings.TrimSpace(m[3])}
+		for _, p := range parameterDocRe.FindAllStringSubmatch(m[2], -1) {
+			doc.Params = append(doc.Params, p[1])
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// MCPServer returns an MCP server exposing the file tools for the current
+// directory. Paths outside the directory are rejected, and the edit tools
+// take a preview parameter that returns the change without applying it.
+func (r *Runner) MCPServer() (*mcp.Server, error) {
+	root, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		return nil, err
+	}
+
+	docs, err := builtinToolDocs()
+	if err != nil {
+		return nil, err
+	}
+	docsByName := make(map[string]ToolDoc)
+	for _, d := range docs {
+		docsByName[d.Name] = d
+	}
+
+	s := mcp.NewServer("code-buddy", "0.1")
+	s.Instructions = fmt.Sprintf("Tools for reading, searching and editing files in %s. Paths are relative to that directory and can't leave it.", root)
+	for _, t := range mcpServeTools {
+		doc, ok := docsByName[t.name]
+		if !ok {
+			return nil, fmt.Errorf("no documentation for tool %s", t.name)
+		}
+		schema := mcp.Schema{
+			Type:       "object",
+			Properties: make(map[string]*mcp.Schema),
+			Required:   t.required,
+		}
+		for _, p := range doc.Params {
+			typ := mcpParamTypes[p]
+			if typ == "" {
+				typ = "string"
+			}
+			schema.Properties[p] = &mcp.Schema{Type: typ}
+		}
+		if t.edit {
+			schema.Properties["preview"] = &mcp.Schema{
+				Type:        "boolean",
+				Description: "Return a preview of the change without applying it.",
+			}
+		}
+		s.AddTool(mcp.Tool{Name: t.name, Description: doc.Description, InputSchema: schema}, r.mcpServeHandler(root, t.name))
+	}
+	return s, nil
+}
+
+func (r *Runner) mcpServeHandler(root, name string) mcp.ToolHandler {
+	return func(ctx context.Context, args map[string]any) (string, error) {
+		var preview bool
+		params := make(map[string]string)
+		for k, v := range args {
+			if k == "preview" {
+				preview, _ = v.(bool)
+				continue
+			}
+			params[k] = mcpParamString(v)
+		}
+
+		cmd, err := r.newCmd(name, params)
+		if err != nil {
+			return "", err
+		}
+		if err := checkToolArgs(root, cmd, params); err != nil {
+			return "", err
+		}
+		if preview {
+			return cmd.PrettyCommand(), nil
+		}
+
+		out, err := runCmd(ctx, cmd)
+		if err != nil {
+			return "", err
+		}
+		if fm, ok := cmd.(fileModifier); ok {
+			if report := r.PostEditChecks.Run(fm.ModifiedFiles()); report != "" {
+				out += "\n\nPost-edit checks:\n" + report
+			}
+		}
+		return out, nil
+	}
+}
+
+// mcpParamString converts a JSON argument to the string form our tools
+// take their parameters in.
+func mcpParamString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case nil:
+		return ""
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
+
+// optionParams are the parameters passed to external commands such as
+// rg. A value that looks like an option, such as --pre=sh, is rejected
+// even though the commands are built to treat it as a value.
+var optionParams = []string{"filename", "directory", "glob", "type"}
+
+// checkToolArgs rejects commands that would read or write outside root or
+// that have a parameter that looks like a command-line option.
+func checkToolArgs(root string, cmd Cmd, params map[string]string) error {
+	for _, name := range optionParams {
+		for _, v := range listParam(params, name) {
+			if strings.HasPrefix(v, "-") {
+				return fmt.Errorf("%s %q looks like a command-line option", name, v)
+			}
+		}
+	}
+
+	paths := []string{params["filename"], params["directory"]}
+	if fm, ok := cmd.(fileModifier); ok {
+		paths = append(paths, fm.ModifiedFiles()...)
+	}
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		if !insideRoot(root, p) {
+			return fmt.Errorf("%s is outside %s", p, root)
+		}
+	}
+	return nil
+}
+
+// insideRoot reports whether p, after resolving symlinks, is root or below
+// it. Paths that don't exist yet are resolved from their closest existing
+// parent.
+func insideRoot(root, p string) bool {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return false
+	}
+	var rest []string
+	for {
+		resolved, err := filepath.EvalSymlinks(abs)
+		if err == nil {
+			abs = filepath.Join(append([]string{resolved}, rest...)...)
+			break
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return false
+		}
+		parent := filepath.Dir(abs)
+		if parent == abs {
+			return false
+		}
+		rest = append([]string{filepath.Base(abs)}, rest...)
+		abs = parent
+	}
+	rel, err := filepath.Rel(root, abs)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,108 @@
+package interactive
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuiltinToolDocs(t *testing.T) {
+	docs, err := builtinToolDocs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := make(map[string]ToolDoc)
+	for _, d := range docs {
+		byName[d.Name] = d
+	}
+	for _, tool := range mcpServeTools {
+		doc, ok := byName[tool.name]
+		if !ok {
+			t.Errorf("no docs for %s", tool.name)
+			continue
+		}
+		if doc.Description == "" {
+			t.Errorf("%s has no description", tool.name)
+		}
+		for _, req := range tool.required {
+			if !strings.Contains(strings.Join(doc.Params, " "), req) {
+				t.Errorf("%s: required parameter %s not documented", tool.name, req)
+			}
+		}
+	}
+	if got := strings.Join(byName["cat"].Params, ","); got != "filename,start_line,end_line" {
+		t.Errorf("cat params = %s", got)
+	}
+}
+
+func TestMCPServeHandler(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	chdir(t, dir)
+
+	if err := os.WriteFile("a.txt", []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, "escape"); err != nil {
+		t.Fatal(err)
+	}
+	root, _ := filepath.EvalSymlinks(dir)
+
+	r := &Runner{}
+	ctx := context.Background()
+	call := func(name string, args map[string]any) (string, error) {
+		return r.mcpServeHandler(root, name)(ctx, args)
+	}
+
+	out, err := call("replace_string_in_file", map[string]any{"filename": "a.txt", "original_string": "hello", "new_string": "bye", "preview": true})
+	if err != nil || !strings.Contains(out, "==== new ====bye") {
+		t.Errorf("preview = %q, %v", out, err)
+	}
+	if content, _ := os.ReadFile("a.txt"); string(content) != "hello\n" {
+		t.Errorf("preview modified the file: %q", content)
+	}
+
+	if _, err := call("replace_string_in_file", map[string]any{"filename": "a.txt", "original_string": "hello", "new_string": "bye", "count": float64(1)}); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile("a.txt"); string(content) != "bye\n" {
+		t.Errorf("after replace = %q", content)
+	}
+
+	for _, tt := range []struct {
+		tool string
+		args map[string]any
+	}{
+		{"rg", map[string]any{"pattern": "x", "directory": "--pre=sh"}},
+		{"rg", map[string]any{"pattern": "x", "glob": "*.go --pre=sh"}},
+		{"cat", map[string]any{"filename": "-"}},
+	} {
+		if _, err := call(tt.tool, tt.args); err == nil || !strings.Contains(err.Error(), "command-line option") {
+			t.Errorf("%s %v: err = %v, want option error", tt.tool, tt.args, err)
+		}
+	}
+
+	for _, tt := range []struct {
+		tool string
+		args map[string]any
+	}{
+		{"cat", map[string]any{"filename": filepath.Join(outside, "x")}},
+		{"cat", map[string]any{"filename": "../x"}},
+		{"write_file", map[string]any{"filename": "escape/new.txt", "content": "x"}},
+		{"rg", map[string]any{"pattern": "x", "directory": "/"}},
+		{"apply_patch", map[string]any{"patch": "--- /dev/null\n+++ b/../evil.txt\n@@ -0,0 +1 @@\n+x\n"}},
+	} {
+		if _, err := call(tt.tool, tt.args); err == nil || !strings.Contains(err.Error(), "outside") {
+			t.Errorf("%s %v: err = %v, want outside root error", tt.tool, tt.args, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outside, "new.txt")); err == nil {
+		t.Errorf("write_file escaped through a symlink")
+	}
+
+	if _, err := call("write_file", map[string]any{"filename": "sub/new.txt", "content": "x"}); err != nil {
+		t.Errorf("write_file new directory: %v", err)
+	}
+}
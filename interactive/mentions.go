@@ -0,0 +1,142 @@
+package interactive
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/chzyer/readline"
+	"github.com/psanford/code-buddy/internal/textutil"
+)
+
+// mentionMaxFileBytes limits the size of a file inlined by an @-mention;
+// the model can read larger files with cat.
+const mentionMaxFileBytes = 100000
+
+// mentionRe matches "@path" at the start of the prompt or after
+// whitespace, so email addresses are left alone.
+var mentionRe = regexp.MustCompile(`(?:^|\s)@(\S+)`)
+
+// expandMentions appends the contents of every file and a listing of
+// every directory mentioned as @path in prompt. Mentions that don't name
+// an existing path are left as plain text. It returns the expanded prompt
+// and a short description of each attachment for the user.
+func expandMentions(prompt string) (string, []string) {
+	var (
+		buf   strings.Builder
+		notes []string
+		seen  = make(map[string]bool)
+	)
+	for _, m := range mentionRe.FindAllStringSubmatch(prompt, -1) {
+		p, info := resolveMention(m[1])
+		if info == nil || seen[p] {
+			continue
+		}
+		seen[p] = true
+
+		if info.IsDir() {
+			tree, err := (&TreeArgs{Directory: p}).Run()
+			if err != nil {
+				notes = append(notes, fmt.Sprintf("@%s: %s", p, err))
+				continue
+			}
+			fmt.Fprintf(&buf, "<directory>\n<path>%s</path>\n<listing>%s</listing>\n</directory>\n", p, tree)
+			notes = append(notes, fmt.Sprintf("@%s (directory listing)", p))
+			continue
+		}
+
+		if info.Size() > mentionMaxFileBytes {
+			notes = append(notes, fmt.Sprintf("@%s: too large to inline (%s), the model can read it with cat", p, textutil.FormatSize(info.Size())))
+			continue
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			notes = append(notes, fmt.Sprintf("@%s: %s", p, err))
+			continue
+		}
+		if isBinaryFile(p) {
+			notes = append(notes, fmt.Sprintf("@%s: binary file, not inlined", p))
+			continue
+		}
+		fmt.Fprintf(&buf, "<file>\n<filename>%s</filename>\n<filecontent>%s</filecontent>\n</file>\n", p, content)
+		notes = append(notes, fmt.Sprintf("@%s (%s)", p, textutil.FormatSize(info.Size())))
+	}
+
+	if buf.Len() == 0 {
+		return prompt, notes
+	}
+	return prompt + "\n\n<mentioned_files>\n" + buf.String() + "</mentioned_files>", notes
+}
+
+// resolveMention returns the path named by a mention and its file info,
+// or a nil info if it doesn't exist. Trailing punctuation is ignored so
+// "look at @main.go." works.
+func resolveMention(mention string) (string, os.FileInfo) {
+	for {
+		if info, err := os.Stat(mention); err == nil {
+			p := filepath.Clean(mention)
+			if info.IsDir() && p != "." {
+				p += string(filepath.Separator)
+			}
+			return p, info
+		}
+		trimmed := strings.TrimRight(mention, ".,;:!?)'\"")
+		if trimmed == mention || trimmed == "" {
+			return "", nil
+		}
+		mention = trimmed
+	}
+}
+
+// mentionCompleter completes @-mentions anywhere in the line with file
+// paths, and defers everything else to the slash command completer.
+type mentionCompleter struct {
+	readline.AutoCompleter
+}
+
+func (c *mentionCompleter) Do(line []rune, pos int) ([][]rune, int) {
+	start := pos
+	for start > 0 && line[start-1] != ' ' && line[start-1] != '\t' && line[start-1] != '\n' {
+		start--
+	}
+	word := string(line[start:pos])
+	if !strings.HasPrefix(word, "@") {
+		return c.AutoCompleter.Do(line, pos)
+	}
+
+	prefix := strings.TrimPrefix(word, "@")
+	matches, _ := filepath.Glob(globEscape(prefix) + "*")
+	showHidden := strings.HasPrefix(prefix[strings.LastIndexByte(prefix, filepath.Separator)+1:], ".")
+	var candidates [][]rune
+	for _, m := range matches {
+		if strings.HasPrefix(filepath.Base(m), ".") && !showHidden {
+			continue
+		}
+		if !strings.HasPrefix(m, prefix) {
+			// Glob cleans the path, e.g. "./x" becomes "x"
+			continue
+		}
+		suffix := strings.TrimPrefix(m, prefix)
+		if info, err := os.Stat(m); err == nil && info.IsDir() {
+			suffix += string(filepath.Separator)
+		} else {
+			suffix += " "
+		}
+		candidates = append(candidates, []rune(suffix))
+	}
+	return candidates, len([]rune(prefix))
+}
+
+// globEscape quotes the glob metacharacters in s.
+func globEscape(s string) string {
+	var buf strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[\`, r) {
+			buf.WriteRune('\\')
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
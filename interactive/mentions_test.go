@@ -0,0 +1,133 @@
+package interactive
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/chzyer/readline"
+)
+
+func TestExpandMentions(t *testing.T) {
+	dir := t.TempDir()
+	chdir(t, dir)
+
+	for name, content := range map[string]string{
+		"main.go":     "package main\n",
+		"pkg/a.go":    "package pkg\n",
+		"pkg/b.go":    "package pkg\n",
+		"data.bin":    "\x00\x01",
+		".hidden.txt": "secret\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		prompt     string
+		expected   []string
+		unexpected []string
+		notes      int
+	}{
+		{
+			name:     "no mentions",
+			prompt:   "explain this project",
+			notes:    0,
+			expected: []string{"explain this project"},
+			unexpected: []string{
+				"<mentioned_files>",
+			},
+		},
+		{
+			name:   "file with trailing punctuation",
+			prompt: "explain @main.go.",
+			notes:  1,
+			expected: []string{
+				"explain @main.go.\n\n<mentioned_files>",
+				"<filename>main.go</filename>\n<filecontent>package main\n</filecontent>",
+			},
+		},
+		{
+			name:   "directory",
+			prompt: "@pkg what is in here? and @pkg/ again",
+			notes:  1,
+			expected: []string{
+				"<path>pkg/</path>",
+				"a.go",
+				"b.go",
+			},
+			unexpected: []string{
+				"<filename>",
+			},
+		},
+		{
+			name:   "unknown mention and email",
+			prompt: "ask @someone or me@main.go",
+			notes:  0,
+			unexpected: []string{
+				"<mentioned_files>",
+			},
+		},
+		{
+			name:   "binary file",
+			prompt: "what is @data.bin",
+			notes:  1,
+			unexpected: []string{
+				"<mentioned_files>",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, notes := expandMentions(tt.prompt)
+			if len(notes) != tt.notes {
+				t.Errorf("notes = %q, want %d", notes, tt.notes)
+			}
+			if !strings.HasPrefix(got, tt.prompt) {
+				t.Errorf("expanded prompt doesn't start with the original prompt: %q", got)
+			}
+			for _, s := range tt.expected {
+				if !strings.Contains(got, s) {
+					t.Errorf("expected %q in:\n%s", s, got)
+				}
+			}
+			for _, s := range tt.unexpected {
+				if strings.Contains(got, s) {
+					t.Errorf("unexpected %q in:\n%s", s, got)
+				}
+			}
+		})
+	}
+
+	c := &mentionCompleter{readline.NewPrefixCompleter(readline.PcItem("/help"))}
+	complete := func(line string) []string {
+		candidates, _ := c.Do([]rune(line), len([]rune(line)))
+		var out []string
+		for _, cand := range candidates {
+			out = append(out, string(cand))
+		}
+		slices.Sort(out)
+		return out
+	}
+
+	if got, want := complete("explain @p"), []string{"kg/"}; !slices.Equal(got, want) {
+		t.Errorf("complete @p = %q, want %q", got, want)
+	}
+	if got, want := complete("explain @pkg/"), []string{"a.go ", "b.go "}; !slices.Equal(got, want) {
+		t.Errorf("complete @pkg/ = %q, want %q", got, want)
+	}
+	if got, want := complete("@"), []string{"data.bin ", "main.go ", "pkg/"}; !slices.Equal(got, want) {
+		t.Errorf("complete @ = %q, want %q", got, want)
+	}
+	if got, want := complete("/he"), []string{"lp "}; !slices.Equal(got, want) {
+		t.Errorf("complete /he = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,516 @@
+package interactive
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type ApplyPatchArgs struct {
+	Patch string `json:"patch"`
+}
+
+func (a *ApplyPatchArgs) PrettyCommand() string {
+	return fmt.Sprintf("patch -p1 <<-EOF\n%s\nEOF", a.Patch)
+}
+
+func (a *ApplyPatchArgs) Run() (string, error) {
+	filePatches, err := parsePatch(a.Patch)
+	if err != nil {
+		return "", err
+	}
+
+	type pendingWrite struct {
+		filename string
+		content  string
+		existed  bool
+		orig     string
+		mode     os.FileMode
+		delete   bool
+	}
+
+	var (
+		writes  []pendingWrite
+		summary []string
+		seen    = make(map[string]bool)
+	)
+
+	// Apply every hunk in memory first so that a single failing hunk
+	// leaves all files untouched.
+	for _, fp := range filePatches {
+		// Each section is applied to the file as it is on disk, so a
+		// second section for the same path would discard the first.
+		for _, name := range []string{fp.oldName, fp.newName} {
+			if name == "" {
+				continue
+			}
+			if seen[filepath.Clean(name)] {
+				return "", fmt.Errorf("%s: patch has more than one section for this file; combine them into one", name)
+			}
+		}
+		for _, name := range []string{fp.oldName, fp.newName} {
+			if name != "" {
+				seen[filepath.Clean(name)] = true
+			}
+		}
+
+		var (
+			orig    string
+			existed bool
+			mode    os.FileMode = 0644
+		)
+		if !fp.isCreate() {
+			info, err := os.Stat(fp.oldName)
+			if err != nil {
+				return "", fmt.Errorf("%s: %w", fp.oldName, err)
+			}
+			b, err := os.ReadFile(fp.oldName)
+			if err != nil {
+				return "", fmt.Errorf("%s: %w", fp.oldName, err)
+			}
+			orig = string(b)
+			existed = true
+			mode = info.Mode().Perm()
+		} else if _, err := os.Stat(fp.newName); err == nil {
+			return "", fmt.Errorf("%s: patch creates file but it already exists", fp.newName)
+		}
+
+		newContent, notes, err := applyFilePatch(orig, fp)
+		if err != nil {
+			return "", err
+		}
+
+		if fp.isDelete() {
+			if strings.TrimSpace(newContent) != "" {
+				return "", fmt.Errorf("%s: patch deletes file but content remains after applying hunks", fp.oldName)
+			}
+			writes = append(writes, pendingWrite{filename: fp.oldName, existed: true, orig: orig, mode: mode, delete: true})
+			summary = append(summary, fmt.Sprintf("%s: deleted", fp.oldName))
+			continue
+		}
+
+		if fp.oldName != fp.newName && !fp.isCreate() {
+			// rename: remove the old path and write the new one
+			writes = append(writes, pendingWrite{filename: fp.oldName, existed: true, orig: orig, mode: mode, delete: true})
+			existed = false
+			orig = ""
+		}
+
+		writes = append(writes, pendingWrite{filename: fp.newName, content: newContent, existed: existed, orig: orig, mode: mode})
+
+		status := fmt.Sprintf("%s: applied %d hunk(s)", fp.newName, len(fp.hunks))
+		if fp.isCreate() {
+			status = fmt.Sprintf("%s: created", fp.newName)
+		} else if fp.oldName != fp.newName {
+			status = fmt.Sprintf("%s -> %s: applied %d hunk(s)", fp.oldName, fp.newName, len(fp.hunks))
+		}
+		for _, note := range notes {
+			status += "\n  " + note
+		}
+		summary = append(summary, status)
+	}
+
+	rollback := func(done []pendingWrite) {
+		for i := len(done) - 1; i >= 0; i-- {
+			w := done[i]
+			if w.existed {
+				os.WriteFile(w.filename, []byte(w.orig), w.mode)
+				// WriteFile only applies the mode to a file it creates
+				os.Chmod(w.filename, w.mode)
+			} else {
+				os.Remove(w.filename)
+			}
+		}
+	}
+
+	for i, w := range writes {
+		var err error
+		if w.delete {
+			err = os.Remove(w.filename)
+		} else {
+			if dir := filepath.Dir(w.filename); dir != "" {
+				err = os.MkdirAll(dir, 0755)
+			}
+			if err == nil {
+				err = os.WriteFile(w.filename, []byte(w.content), w.mode)
+			}
+		}
+		if err != nil {
+			rollback(writes[:i])
+			return "", fmt.Errorf("write %s err: %w (no files were modified)", w.filename, err)
+		}
+	}
+
+	return fmt.Sprintf("Patch applied successfully.\n%s", strings.Join(summary, "\n")), nil
+}
+
+type filePatch struct {
+	oldName string
+	newName string
+	hunks   []*hunk
+}
+
+func (fp *filePatch) isCreate() bool {
+	return fp.oldName == ""
+}
+
+func (fp *filePatch) isDelete() bool {
+	return fp.newName == ""
+}
+
+func (fp *filePatch) name() string {
+	if fp.newName != "" {
+		return fp.newName
+	}
+	return fp.oldName
+}
+
+type hunk struct {
+	header   string
+	oldStart int
+	oldCount int
+	lines    []hunkLine
+
+	noNewlineOld bool
+	noNewlineNew bool
+}
+
+type hunkLine struct {
+	op   byte // ' ', '-' or '+'
+	text string
+}
+
+func (h *hunk) oldLines() []string {
+	var out []string
+	for _, l := range h.lines {
+		if l.op != '+' {
+			out = append(out, l.text)
+		}
+	}
+	return out
+}
+
+func (h *hunk) newLines() []string {
+	var out []string
+	for _, l := range h.lines {
+		if l.op != '-' {
+			out = append(out, l.text)
+		}
+	}
+	return out
+}
+
+var hunkHeaderRegex = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// parsePatch parses a unified diff that may touch multiple files.
+func parsePatch(patch string) ([]*filePatch, error) {
+	lines := strings.Split(strings.ReplaceAll(patch, "\r\n", "\n"), "\n")
+
+	var (
+		files []*filePatch
+		cur   *filePatch
+		h     *hunk
+
+		oldRemaining, newRemaining int
+	)
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		lineNo := i + 1
+
+		if h != nil && (oldRemaining > 0 || newRemaining > 0) {
+			if line == "" {
+				// tolerate blank context lines that lost their leading space
+				line = " "
+			}
+			switch line[0] {
+			case ' ':
+				oldRemaining--
+				newRemaining--
+			case '-':
+				oldRemaining--
+			case '+':
+				newRemaining--
+			case '\\':
+				markNoNewline(h)
+				continue
+			default:
+				return nil, fmt.Errorf("patch line %d: hunk %s ended early: expected %d more old and %d more new lines, got %q", lineNo, h.header, oldRemaining, newRemaining, line)
+			}
+			h.lines = append(h.lines, hunkLine{op: line[0], text: line[1:]})
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, `\`):
+			if h != nil {
+				markNoNewline(h)
+			}
+		case strings.HasPrefix(line, "--- "):
+			if i+1 >= len(lines) || !strings.HasPrefix(lines[i+1], "+++ ") {
+				return nil, fmt.Errorf("patch line %d: '---' header not followed by '+++' header", lineNo)
+			}
+			cur = &filePatch{
+				oldName: patchFileName(line[4:]),
+				newName: patchFileName(lines[i+1][4:]),
+			}
+			if cur.oldName == "" && cur.newName == "" {
+				return nil, fmt.Errorf("patch line %d: both file names are /dev/null", lineNo)
+			}
+			files = append(files, cur)
+			h = nil
+			i++
+		case strings.HasPrefix(line, "@@"):
+			if cur == nil {
+				return nil, fmt.Errorf("patch line %d: hunk header before any '---'/'+++' file header", lineNo)
+			}
+			m := hunkHeaderRegex.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("patch line %d: malformed hunk header %q", lineNo, line)
+			}
+			h = &hunk{header: line}
+			h.oldStart, _ = strconv.Atoi(m[1])
+			h.oldCount = 1
+			if m[2] != "" {
+				h.oldCount, _ = strconv.Atoi(m[2])
+			}
+			newCount := 1
+			if m[4] != "" {
+				newCount, _ = strconv.Atoi(m[4])
+			}
+			oldRemaining, newRemaining = h.oldCount, newCount
+			cur.hunks = append(cur.hunks, h)
+		default:
+			// Ignore git extended headers (diff --git, index, mode lines)
+			// and any surrounding prose.
+		}
+	}
+
+	if h != nil && (oldRemaining > 0 || newRemaining > 0) {
+		return nil, fmt.Errorf("hunk %s in %s is truncated: expected %d more old and %d more new lines", h.header, cur.name(), oldRemaining, newRemaining)
+	}
+	if len(files) == 0 {
+		return nil, errors.New("no file headers ('--- a/file' / '+++ b/file') found in patch")
+	}
+	for _, fp := range files {
+		if len(fp.hunks) == 0 {
+			return nil, fmt.Errorf("%s: no hunks found in patch", fp.name())
+		}
+	}
+
+	return files, nil
+}
+
+func markNoNewline(h *hunk) {
+	if len(h.lines) == 0 {
+		return
+	}
+	switch h.lines[len(h.lines)-1].op {
+	case '-':
+		h.noNewlineOld = true
+	case '+':
+		h.noNewlineNew = true
+	default:
+		h.noNewlineOld = true
+		h.noNewlineNew = true
+	}
+}
+
+func patchFileName(s string) string {
+	// strip optional timestamp separated by a tab
+	if idx := strings.Index(s, "\t"); idx >= 0 {
+		s = s[:idx]
+	}
+	s = strings.TrimSpace(s)
+	if s == "/dev/null" {
+		return ""
+	}
+	if strings.HasPrefix(s, "a/") || strings.HasPrefix(s, "b/") {
+		s = s[2:]
+	}
+	return s
+}
+
+// matchLevel controls how strictly hunk context must match file content.
+type matchLevel int
+
+const (
+	matchExact matchLevel = iota
+	matchIgnoreTrailingSpace
+	matchIgnoreSpace
+)
+
+func (m matchLevel) normalize(s string) string {
+	switch m {
+	case matchIgnoreTrailingSpace:
+		return strings.TrimRight(s, " \t")
+	case matchIgnoreSpace:
+		return strings.Join(strings.Fields(s), " ")
+	}
+	return s
+}
+
+// applyFilePatch applies all hunks of fp to content. It returns the new
+// content along with notes about hunks that needed an offset or fuzzy
+// whitespace matching to apply.
+func applyFilePatch(content string, fp *filePatch) (string, []string, error) {
+	var lines []string
+	trailingNewline := true
+	if content != "" {
+		lines = strings.Split(content, "\n")
+		if lines[len(lines)-1] == "" {
+			lines = lines[:len(lines)-1]
+		} else {
+			trailingNewline = false
+		}
+	}
+
+	var (
+		out    []string
+		notes  []string
+		cursor int // index of the first line of lines not yet copied to out
+		offset int // difference between expected and actual position of prior hunks
+	)
+
+	for hi, h := range fp.hunks {
+		old := h.oldLines()
+
+		expected := h.oldStart - 1 + offset
+		if h.oldCount == 0 {
+			// for pure insertions the start line is the line after which to insert
+			expected = h.oldStart + offset
+		}
+
+		pos, level := findHunk(lines, old, expected, cursor)
+		if pos < 0 {
+			return "", nil, hunkMismatchError(fp, hi, h, lines, old, expected, cursor)
+		}
+
+		if delta := pos - expected; delta != 0 || level != matchExact {
+			note := fmt.Sprintf("hunk %d applied at line %d", hi+1, pos+1)
+			if delta != 0 {
+				note += fmt.Sprintf(" (offset %+d lines)", delta)
+			}
+			if level != matchExact {
+				note += " (whitespace differences ignored)"
+			}
+			notes = append(notes, note)
+		}
+		offset = pos - (h.oldStart - 1)
+		if h.oldCount == 0 {
+			offset = pos - h.oldStart
+		}
+
+		out = append(out, lines[cursor:pos]...)
+		out = append(out, h.newLines()...)
+		cursor = pos + len(old)
+
+		if cursor == len(lines) {
+			if h.noNewlineNew {
+				trailingNewline = false
+			} else if h.noNewlineOld {
+				trailingNewline = true
+			}
+		}
+	}
+	out = append(out, lines[cursor:]...)
+
+	if len(out) == 0 {
+		return "", notes, nil
+	}
+	result := strings.Join(out, "\n")
+	if trailingNewline {
+		result += "\n"
+	}
+	return result, notes, nil
+}
+
+// findHunk looks for old within lines, searching outward from expected and
+// never before minPos. Exact matches anywhere are preferred over fuzzy ones.
+func findHunk(lines, old []string, expected, minPos int) (int, matchLevel) {
+	if len(old) == 0 {
+		if expected < minPos {
+			expected = minPos
+		}
+		if expected > len(lines) {
+			expected = len(lines)
+		}
+		return expected, matchExact
+	}
+
+	for _, level := range []matchLevel{matchExact, matchIgnoreTrailingSpace, matchIgnoreSpace} {
+		for d := 0; d <= len(lines); d++ {
+			for _, pos := range []int{expected + d, expected - d} {
+				if pos < minPos || pos+len(old) > len(lines) {
+					continue
+				}
+				if linesMatch(lines[pos:pos+len(old)], old, level) {
+					return pos, level
+				}
+				if d == 0 {
+					break
+				}
+			}
+		}
+	}
+	return -1, matchExact
+}
+
+func linesMatch(a, b []string, level matchLevel) bool {
+	for i := range b {
+		if level.normalize(a[i]) != level.normalize(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func hunkMismatchError(fp *filePatch, hi int, h *hunk, lines, old []string, expected, minPos int) error {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "%s: hunk %d of %d (%s) failed to apply: context and removed lines were not found in the file", fp.name(), hi+1, len(fp.hunks), h.header)
+
+	// Report the closest candidate: the position with the most matching lines.
+	bestPos, bestScore := -1, 0
+	for pos := minPos; pos+len(old) <= len(lines); pos++ {
+		var score int
+		for i := range old {
+			if matchIgnoreSpace.normalize(lines[pos+i]) == matchIgnoreSpace.normalize(old[i]) {
+				score++
+			}
+		}
+		if score > bestScore || (score == bestScore && score > 0 && abs(pos-expected) < abs(bestPos-expected)) {
+			bestPos, bestScore = pos, score
+		}
+	}
+
+	if bestPos < 0 {
+		// Nothing matched anywhere; compare against the expected location.
+		bestPos = min(max(expected, minPos), len(lines)-len(old))
+		if bestPos < 0 {
+			fmt.Fprintf(&buf, "\nthe hunk expects %d lines but only %d lines are available", len(old), len(lines)-minPos)
+			return errors.New(buf.String())
+		}
+		fmt.Fprintf(&buf, "\nno lines of the hunk match; comparing with line %d:", bestPos+1)
+	} else {
+		fmt.Fprintf(&buf, "\nclosest match at line %d (%d of %d lines match):", bestPos+1, bestScore, len(old))
+	}
+	for i := range old {
+		actual := lines[bestPos+i]
+		if actual == old[i] {
+			continue
+		}
+		fmt.Fprintf(&buf, "\n  line %d: expected %q\n  line %d: actual   %q", bestPos+i+1, old[i], bestPos+i+1, actual)
+	}
+	return errors.New(buf.String())
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
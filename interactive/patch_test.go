@@ -0,0 +1,178 @@
+package interactive
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplyPatch(t *testing.T) {
+	dir := t.TempDir()
+	chdir(t, dir)
+
+	writeFile := func(name, content string) {
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	readFile := func(name string) string {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	writeFile("a.go", "package a\n\nfunc A() int {\n\treturn 1\n}\n")
+	// b.go has extra lines at the top so the hunk applies at an offset,
+	// and trailing whitespace so context only matches fuzzily.
+	writeFile("b.go", "// header\n// header\npackage b\n\nfunc B() int {  \n\treturn 2\n}\n")
+
+	patch := `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -3,3 +3,3 @@
+ func A() int {
+-	return 1
++	return 10
+ }
+--- a/b.go
++++ b/b.go
+@@ -3,3 +3,3 @@
+ func B() int {
+-	return 2
++	return 20
+ }
+--- /dev/null
++++ b/sub/c.go
+@@ -0,0 +1,1 @@
++package sub
+`
+
+	out, err := (&ApplyPatchArgs{Patch: patch}).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "offset +2 lines") || !strings.Contains(out, "whitespace differences ignored") {
+		t.Errorf("expected offset and fuzz notes in output, got %s", out)
+	}
+
+	if got, want := readFile("a.go"), "package a\n\nfunc A() int {\n\treturn 10\n}\n"; got != want {
+		t.Errorf("a.go got %q want %q", got, want)
+	}
+	if got, want := readFile("b.go"), "// header\n// header\npackage b\n\nfunc B() int {\n\treturn 20\n}\n"; got != want {
+		t.Errorf("b.go got %q want %q", got, want)
+	}
+	if got, want := readFile(filepath.Join("sub", "c.go")), "package sub\n"; got != want {
+		t.Errorf("sub/c.go got %q want %q", got, want)
+	}
+
+	// The second hunk doesn't match so neither file should change.
+	failPatch := `--- a/a.go
++++ b/a.go
+@@ -4,1 +4,1 @@
+-	return 10
++	return 100
+--- a/b.go
++++ b/b.go
+@@ -6,1 +6,1 @@
+-	return 3
++	return 30
+`
+	_, err = (&ApplyPatchArgs{Patch: failPatch}).Run()
+	if err == nil {
+		t.Fatal("expected error for non-matching hunk")
+	}
+	if !strings.Contains(err.Error(), "b.go: hunk 1 of 1") || !strings.Contains(err.Error(), `expected "\treturn 3"`) {
+		t.Errorf("unexpected error message: %s", err)
+	}
+	if got := readFile("a.go"); !strings.Contains(got, "return 10\n") {
+		t.Errorf("a.go was modified by failed patch: %q", got)
+	}
+
+	deletePatch := `--- a/sub/c.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package sub
+`
+	_, err = (&ApplyPatchArgs{Patch: deletePatch}).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join("sub", "c.go")); !os.IsNotExist(err) {
+		t.Errorf("expected sub/c.go to be deleted, stat err: %v", err)
+	}
+
+	// Two sections for one file would each start from the original, so
+	// the patch is rejected rather than losing the first section.
+	dupPatch := `--- a/a.go
++++ b/a.go
+@@ -1,1 +1,1 @@
+-package a
++package aa
+--- a/a.go
++++ b/a.go
+@@ -4,1 +4,1 @@
+-	return 10
++	return 100
+`
+	_, err = (&ApplyPatchArgs{Patch: dupPatch}).Run()
+	if err == nil || !strings.Contains(err.Error(), "more than one section") {
+		t.Errorf("expected duplicate section error, got %v", err)
+	}
+	if got := readFile("a.go"); !strings.HasPrefix(got, "package a\n") || !strings.Contains(got, "return 10\n") {
+		t.Errorf("a.go was modified by rejected patch: %q", got)
+	}
+
+	// Renaming onto a path under a regular file fails after the old file
+	// was removed; rollback must restore it with its original mode.
+	writeFile("run.sh", "#!/bin/sh\n")
+	os.Chmod("run.sh", 0755)
+	writeFile("blocker", "")
+	renamePatch := `--- a/run.sh
++++ b/blocker/run.sh
+@@ -1 +1 @@
+-#!/bin/sh
++#!/bin/bash
+`
+	if _, err := (&ApplyPatchArgs{Patch: renamePatch}).Run(); err == nil {
+		t.Fatal("expected error renaming under a regular file")
+	}
+	info, err := os.Stat("run.sh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("run.sh mode after rollback = %v, want 0755", info.Mode().Perm())
+	}
+}
+
+func TestParsePatchErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+	}{
+		{
+			name:  "no headers",
+			patch: "@@ -1 +1 @@\n-a\n+b\n",
+		},
+		{
+			name:  "truncated hunk",
+			patch: "--- a/x\n+++ b/x\n@@ -1,3 +1,3 @@\n a\n-b\n",
+		},
+		{
+			name:  "no hunks",
+			patch: "--- a/x\n+++ b/x\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parsePatch(tt.patch)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package interactive
+
+import (
+	"errors"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/imports"
+)
+
+// fileModifier is implemented by commands that write files so that
+// post-edit checks can be run on the files they touched and the changes can
+// be committed. ModifiedFiles includes paths that were removed.
+type fileModifier interface {
+	ModifiedFiles() []string
+}
+
+func (a *ModifyFileArgs) ModifiedFiles() []string          { return []string{a.Filename} }
+func (a *AppendToFileArgs) ModifiedFiles() []string        { return []string{a.Filename} }
+func (a *ReplaceStringInFileArgs) ModifiedFiles() []string { return []string{a.Filename} }
+func (a *EditFileArgs) ModifiedFiles() []string            { return []string{a.Filename} }
+func (a *MoveFileArgs) ModifiedFiles() []string            { return []string{a.Source, a.Destination} }
+func (a *CopyFileArgs) ModifiedFiles() []string            { return []string{a.Destination} }
+func (a *DeleteFileArgs) ModifiedFiles() []string          { return []string{a.Filename} }
+
+func (a *ApplyPatchArgs) ModifiedFiles() []string {
+	filePatches, err := parsePatch(a.Patch)
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, fp := range filePatches {
+		if !fp.isCreate() && fp.oldName != fp.newName {
+			files = append(files, fp.oldName)
+		}
+		if !fp.isDelete() {
+			files = append(files, fp.newName)
+		}
+	}
+	return files
+}
+
+// PostEditChecks configures the checks run after a command modifies Go files.
+type PostEditChecks struct {
+	// Gofmt formats modified files in place and reports syntax errors.
+	Gofmt bool
+	// Goimports formats with goimports instead of gofmt, which also adds
+	// and removes imports.
+	Goimports bool
+	// Vet runs go vet on the packages containing modified files.
+	Vet bool
+	// Build runs go build on the packages containing modified files.
+	Build bool
+	// Timeout limits how long go vet and go build may run.
+	Timeout time.Duration
+}
+
+const defaultPostEditTimeout = 2 * time.Minute
+
+// Enabled reports whether any check is turned on.
+func (c *PostEditChecks) Enabled() bool {
+	return c.Gofmt || c.Goimports || c.Vet || c.Build
+}
+
+// Run checks the Go files among files and returns a report of anything
+// notable, or an empty string if there is nothing to report.
+func (c *PostEditChecks) Run(files []string) string {
+	var (
+		report  []string
+		pkgDirs = make(map[string]bool)
+	)
+
+	for _, file := range files {
+		if !strings.HasSuffix(file, ".go") {
+			continue
+		}
+		if _, err := os.Stat(file); err != nil {
+			continue
+		}
+		pkgDirs[filepath.Dir(file)] = true
+
+		if c.Gofmt || c.Goimports {
+			if msg := c.format(file); msg != "" {
+				report = append(report, msg)
+			}
+		}
+	}
+
+	if len(pkgDirs) == 0 {
+		return ""
+	}
+
+	dirs := make([]string, 0, len(pkgDirs))
+	for dir := range pkgDirs {
+		if !filepath.IsAbs(dir) && !strings.HasPrefix(dir, ".") {
+			dir = "./" + dir
+		}
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	if c.Build {
+		if out := c.goCmd("build", dirs); out != "" {
+			report = append(report, out)
+		}
+	}
+	if c.Vet {
+		if out := c.goCmd("vet", dirs); out != "" {
+			report = append(report, out)
+		}
+	}
+
+	return strings.Join(report, "\n")
+}
+
+func (c *PostEditChecks) format(file string) string {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Sprintf("gofmt: %s", err)
+	}
+
+	var (
+		formatted []byte
+		tool      = "gofmt"
+	)
+	if c.Goimports {
+		tool = "goimports"
+		formatted, err = imports.Process(file, src, nil)
+	} else {
+		formatted, err = format.Source(src)
+	}
+	if err != nil {
+		// format.Source errors don't include the file name
+		msg := err.Error()
+		if !strings.HasPrefix(msg, file) {
+			msg = file + ":" + msg
+		}
+		return fmt.Sprintf("%s: syntax error: %s", tool, msg)
+	}
+
+	if string(formatted) == string(src) {
+		return ""
+	}
+	err = os.WriteFile(file, formatted, 0644)
+	if err != nil {
+		return fmt.Sprintf("%s: write %s err: %s", tool, file, err)
+	}
+	return fmt.Sprintf("%s: reformatted %s", tool, file)
+}
+
+func (c *PostEditChecks) goCmd(subcmd string, dirs []string) string {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = defaultPostEditTimeout
+	}
+
+	args := append([]string{subcmd}, dirs...)
+	stdout, stderr, err := runCmdTimeout(timeout, "go", args...)
+	if err == nil {
+		return ""
+	}
+	if errors.Is(err, errCmdTimeout) {
+		return fmt.Sprintf("go %s: %s", subcmd, err)
+	}
+	out := strings.TrimSpace(string(stdout) + string(stderr))
+	return fmt.Sprintf("go %s %s failed:\n%s", subcmd, strings.Join(dirs, " "), out)
+}
@@ -0,0 +1,51 @@
+package interactive
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPostEditGofmt(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.go")
+	bad := filepath.Join(dir, "bad.go")
+	notGo := filepath.Join(dir, "notes.txt")
+
+	files := map[string]string{
+		good:  "package x\n\nfunc  A( ) {\nreturn\n}\n",
+		bad:   "package x\n\nfunc B() {\n",
+		notGo: "func  C( ) {",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := PostEditChecks{Gofmt: true}
+	report := c.Run([]string{good, bad, notGo})
+
+	if !strings.Contains(report, "gofmt: reformatted "+good) {
+		t.Errorf("expected reformat notice, got:\n%s", report)
+	}
+	if !strings.Contains(report, "gofmt: syntax error: "+bad+":3:12") {
+		t.Errorf("expected syntax error for bad.go, got:\n%s", report)
+	}
+	if strings.Contains(report, "notes.txt") {
+		t.Errorf("non-go file should not be checked, got:\n%s", report)
+	}
+
+	b, err := os.ReadFile(good)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "package x\n\nfunc A() {\n\treturn\n}\n" {
+		t.Errorf("good.go not formatted: %q", b)
+	}
+
+	if report := c.Run([]string{good}); report != "" {
+		t.Errorf("expected empty report for formatted file, got:\n%s", report)
+	}
+}
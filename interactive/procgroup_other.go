@@ -0,0 +1,10 @@
+//go:build !unix
+
+package interactive
+
+import "os/exec"
+
+// killProcessGroup is a no-op where process groups aren't supported; the
+// command's WaitDelay still bounds how long its children can hold its
+// output open.
+func killProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,89 @@
+package interactive
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/psanford/code-buddy/repomap"
+)
+
+// projectContext holds the parts of the system prompt that need a walk of
+// the project: the file list, the directory tree, the repo map and the
+// paths of nested instructions files. They are only rebuilt after something may have changed the files: an edit by
+// the assistant, a ! command or /refresh.
+type projectContext struct {
+	project string
+	repoMap *repomap.Cache // nil if the repo map is disabled
+
+	stale       bool
+	fileCount   int
+	firstFiles  []string
+	tree        string
+	repoMapText string
+	// instructions files below the working directory; their contents
+	// are still read every turn
+	nestedInstructions []string
+}
+
+func newProjectContext(project string, repoMap *repomap.Cache) *projectContext {
+	return &projectContext{
+		project:   project,
+		repoMap:   repoMap,
+		stale:     true,
+		fileCount: -1,
+	}
+}
+
+// invalidate makes the next refresh walk the project again.
+func (c *projectContext) invalidate() {
+	c.stale = true
+}
+
+// refreshProjectContext rebuilds c if it is stale. A failed walk is reported
+// as a warning rather than ending the session: the previous context is
+// kept and the walk is retried on the next refresh.
+func (r *Runner) refreshProjectContext(c *projectContext) {
+	if !c.stale {
+		return
+	}
+	if err := r.walkProjectContext(c); err != nil {
+		fmt.Printf("project context err: %s; the system prompt may be out of date\n", err)
+		return
+	}
+	c.stale = false
+}
+
+func (r *Runner) walkProjectContext(c *projectContext) error {
+	if strings.HasSuffix(c.project, ".git") {
+		rgOut, err := projectFiles()
+		if err != nil {
+			return err
+		}
+		rgFileLines := strings.Split(strings.TrimSpace(string(rgOut)), "\n")
+		fileCount := len(rgFileLines)
+		if fileCount > 10 {
+			rgFileLines = rgFileLines[:10]
+		}
+
+		tree, err := r.projectTree()
+		if err != nil {
+			return err
+		}
+		c.fileCount, c.firstFiles, c.tree = fileCount, rgFileLines, tree
+	}
+
+	if c.repoMap != nil {
+		repoMap, err := c.repoMap.String()
+		if err != nil {
+			return err
+		}
+		c.repoMapText = repoMap
+	}
+
+	nested, err := nestedInstructions(".")
+	if err != nil {
+		return err
+	}
+	c.nestedInstructions = nested
+	return nil
+}
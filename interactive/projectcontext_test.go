@@ -0,0 +1,66 @@
+package interactive
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/psanford/code-buddy/repomap"
+)
+
+func TestProjectContextRefresh(t *testing.T) {
+	dir := t.TempDir()
+	chdir(t, dir)
+
+	origRG := rgAvailable
+	defer func() { rgAvailable = origRG }()
+	rgAvailable = func() bool { return false }
+
+	if err := os.WriteFile("a.txt", []byte("a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &Runner{}
+	pc := newProjectContext("example.git", nil)
+	r.refreshProjectContext(pc)
+	if pc.fileCount != 1 || pc.stale {
+		t.Fatalf("fileCount = %d, stale = %t after first refresh", pc.fileCount, pc.stale)
+	}
+
+	if err := os.WriteFile("b.txt", []byte("b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r.refreshProjectContext(pc)
+	if pc.fileCount != 1 {
+		t.Errorf("fileCount = %d, expected the cached count until invalidated", pc.fileCount)
+	}
+	pc.invalidate()
+	r.refreshProjectContext(pc)
+	if pc.fileCount != 2 {
+		t.Errorf("fileCount = %d after invalidate, want 2", pc.fileCount)
+	}
+
+	if err := os.MkdirAll("sub", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join("sub", instructionsFileName), []byte("use tabs\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pc.invalidate()
+	r.refreshProjectContext(pc)
+	if want := []string{"sub/" + instructionsFileName}; !slices.Equal(pc.nestedInstructions, want) {
+		t.Errorf("nestedInstructions = %q, want %q", pc.nestedInstructions, want)
+	}
+
+	// a failed walk keeps the session going and is retried next time
+	broken := newProjectContext("example.git", &repomap.Cache{Root: "missing"})
+	r.refreshProjectContext(broken)
+	if !broken.stale {
+		t.Errorf("context not stale after a failed walk")
+	}
+	prompt := r.buildSystemPrompt(broken, nil, nil)
+	if prompt == "" {
+		t.Errorf("empty prompt after a failed walk")
+	}
+}
@@ -10,56 +10,97 @@ type SystemPromptBuilder struct {
 	Project             string
 	FileCount           int
 	FirstFilesInProject []string
+	ProjectTree         string
+	RepoMap             string
+	Git                 *GitInfo
+	Tools               []string // tools offered to the model, nil for all
+	MCPTools            []ToolDoc
 	FunctionCallPrefix  string
 	FilesContent        []FileContent
+	Instructions        []InstructionsFile
 	Date                string
 	PunMode             bool
+	DisableTools        bool // leave the tools and project context out
+	PostEditChecks      PostEditChecks
 
 	Template *template.Template
 }
 
+// ToolDoc describes a tool that isn't built in, such as one provided by an
+// MCP server.
+type ToolDoc struct {
+	Name        string
+	Params      []string
+	Description string
+}
+
 type FileContent struct {
 	FileName string
 	Content  string
 }
 
 func newSystemPromptBuilder(project, customTemplateText string) *SystemPromptBuilder {
-	tmpl := template.Must(template.New("").Parse(genericTemplate))
-	template.Must(tmpl.New("file_contents").Parse(fileContentsTmpl))
-
-	if customTemplateText != "" {
-		template.Must(tmpl.New("custom_template").Parse(customTemplateText))
-	} else {
-		template.Must(tmpl.New("custom_template").Parse(systemPromptTemplate))
-	}
-
 	return &SystemPromptBuilder{
 		Project:            project,
 		FileCount:          -1,
 		FunctionCallPrefix: reverseString("function_call"),
 		Date:               time.Now().Format("2006-01-02"),
-		Template:           tmpl,
+		Template:           template.Must(parsePromptTemplate(customTemplateText)),
 	}
 }
 
+// parsePromptTemplate parses customTemplateText as the body of the system
+// prompt, or the default prompt if it is empty.
+func parsePromptTemplate(customTemplateText string) (*template.Template, error) {
+	tmpl := template.Must(template.New("").Parse(genericTemplate))
+	template.Must(tmpl.New("file_contents").Parse(fileContentsTmpl))
+	template.Must(tmpl.New("instructions").Parse(instructionsTmpl))
+	template.Must(tmpl.New("context").Parse(contextTmpl))
+	template.Must(tmpl.New("tools").Parse(toolsTmpl))
+	template.Must(tmpl.New("additional_rules").Parse(additionalRulesTmpl))
+
+	if customTemplateText == "" {
+		customTemplateText = systemPromptTemplate
+	}
+	if _, err := tmpl.New("custom_template").Parse(customTemplateText); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
 func (b *SystemPromptBuilder) IncludeProjectContext() bool {
-	return len(b.FilesContent) == 0
+	return !b.DisableTools
 }
 func (b *SystemPromptBuilder) IncludeFSTools() bool {
-	return len(b.FilesContent) == 0
+	return !b.DisableTools
+}
+
+// HasTool reports whether the named tool is offered to the model.
+func (b *SystemPromptBuilder) HasTool(name string) bool {
+	return toolAllowed(b.Tools, name)
 }
 
 func (b *SystemPromptBuilder) String() string {
-	var buf bytes.Buffer
-	err := b.Template.Execute(&buf, b)
+	s, err := b.Render()
 	if err != nil {
 		panic(err)
 	}
-	return buf.String()
+	return s
+}
+
+// Render executes the template. Unlike String it returns an error, since
+// a user provided template may reference fields that don't exist.
+func (b *SystemPromptBuilder) Render() (string, error) {
+	var buf bytes.Buffer
+	if err := b.Template.Execute(&buf, b); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
 }
 
 var genericTemplate = `
 {{template "custom_template" .}}
+{{template "instructions" .}}
 {{template "file_contents" .}}
 Today's date is {{.Date}}
 `
@@ -72,16 +113,30 @@ You also enjoy a good pun on occasion.
 
  Your first task is to devise a plan for how you will solve this task. Generate a list of steps to perform. You can revise this list later as you learn new things along the way.
 
-Generate all of the relevant information necessary to pass along to another software engineering assistant so that it can pick up and perform the next step in the instructions. That assistant will have no additional context besides what you provide so be sure to include all relevant information necessary to perform the next step.
+Generate all of the relevant information necessary to pass along to another software engineering assistant so that it can pick up and perform the next step in the instructions. That assistant will have no additional context besides what you provide so be sure to include all relevant information necessary to perform the next step.{{if and .IncludeFSTools (.HasTool "delegate")}} Use the delegate tool to hand research steps to such an assistant.{{end}}
 
 Prefer making multiple smaller changes to one large change when you only need to update a few small parts of the code you are working on.
 
-{{if .IncludeProjectContext}}
+{{template "context" .}}
+
+{{template "tools" .}}
+
+{{template "additional_rules" .}}
+`
+
+// contextTmpl, toolsTmpl and additionalRulesTmpl make up the default
+// prompt. Custom prompt templates can include them with
+// {{template "context" .}}, {{template "tools" .}} and
+// {{template "additional_rules" .}}.
+var contextTmpl = `{{if .IncludeProjectContext}}
 <context>
 {{- if not (eq .Project "")}}
 project={{.Project}}
 {{- end}}
-{{if gt (len .FirstFilesInProject) 0}}
+{{if .ProjectTree}}
+project structure (directories show file count and total size):
+{{.ProjectTree}}
+{{- else if gt (len .FirstFilesInProject) 0}}
 first 10 files in project:
 {{range .FirstFilesInProject -}}
 {{.}}
@@ -90,10 +145,18 @@ first 10 files in project:
 {{if gt .FileCount -1 -}}
 file_count={{.FileCount}}
 {{end -}}
+{{if .Git}}
+git repository state:
+{{.Git}}
+{{- end -}}
+{{if .RepoMap}}
+go packages and their exported API, most imported first:
+{{.RepoMap}}
+{{- end}}
 </context>
-{{end}}
+{{end}}`
 
-{{if .IncludeFSTools}}
+var toolsTmpl = `{{if .IncludeFSTools}}
 In this environment, you can invoke tools using the following syntax:
 #{{.FunctionCallPrefix}},function,$FUNCTION_NAME
 #{{.FunctionCallPrefix}},parameter,$PARAM_NAME1
@@ -118,45 +181,132 @@ The response will be in the form:
 
 The available functions that you can invoke this way are:
 
-<function name="write_file">
+{{if .HasTool "write_file"}}<function name="write_file">
 <parameter name="filename"/>
 <parameter name="content"/>
-<description>Modify the full contents of a file. You MUST provide the full contents of the file!</description>
+<description>Modify the full contents of a file. You MUST provide the full contents of the file! Missing parent directories are created.</description>
 </function>
 
-<function name="append_to_file">
+{{end}}{{if .HasTool "append_to_file"}}<function name="append_to_file">
 <parameter name="filename"/>
 <parameter name="content"/>
 <description>Append content to the end of a file.</description>
 </function>
 
-<function name="replace_string_in_file">
+{{end}}{{if .HasTool "replace_string_in_file"}}<function name="replace_string_in_file">
 <parameter name="filename"/>
 <parameter name="original_string"/>
 <parameter name="new_string"/>
 <parameter name="count"/>
-<description>Partially modify the contents of a file. This works the same way as Go's string.Replace() function: Replace returns a copy of the string s with the first n non-overlapping instances of old replaced by new. If old is empty, it matches at the beginning of the string and after each UTF-8 sequence, yielding up to k+1 replacements for a k-rune string. If n < 0, there is no limit on the number of replacements.
+<description>Partially modify the contents of a file. This works the same way as Go's string.Replace() function: Replace returns a copy of the string s with the first n non-overlapping instances of old replaced by new. If n < 0, there is no limit on the number of replacements. count defaults to 1. It is an error if original_string is not found in the file.
 You should prefer this function to write_file whenever you are making partial updates to a file.
 </description>
-</function
+</function>
+
+{{end}}{{if .HasTool "edit_file"}}<function name="edit_file">
+<parameter name="filename"/>
+<parameter name="edits"/>
+<description>Apply one or more exact search and replace edits to a file, in order. Each edit in the edits parameter has the form:
+<<<<<<< SEARCH
+$OLD_TEXT
+=======
+$NEW_TEXT
+>>>>>>> REPLACE
+$OLD_TEXT must match the file exactly, including whitespace and indentation, and must occur exactly once. If it occurs more than once, add surrounding lines to make it unique or put the line number where the intended occurrence starts after SEARCH (for example "<<<<<<< SEARCH 42"); line numbers refer to the file before any of the edits are applied. If any edit fails no changes are written, and the result lists similar lines from the file to help you correct the edit.
+</description>
+</function>
+
+{{end}}{{if .HasTool "apply_patch"}}<function name="apply_patch">
+<parameter name="patch"/>
+<description>Apply a unified diff (the format produced by "diff -u" or "git diff") to one or more files. Each file section must start with "--- a/$FILENAME" and "+++ b/$FILENAME" headers followed by one or more "@@ -l,s +l,s @@" hunks. Use /dev/null as the old file name to create a file, or as the new file name to delete one. Include a few lines of unchanged context around each change. Hunks may apply at a different line than stated in the header and whitespace differences in context are tolerated. Either every hunk applies or no files are modified; on failure the result describes which hunk did not match.
+This is the most efficient way to make several edits, especially across multiple files.
+</description>
+</function>
 
-<function name="list_files">
+{{end}}{{if .HasTool "mkdir"}}<function name="mkdir">
+<parameter name="path"/>
+<description>Create a directory, including any missing parent directories.</description>
+</function>
+
+{{end}}{{if .HasTool "move_file"}}<function name="move_file">
+<parameter name="source"/>
+<parameter name="destination"/>
+<description>Move or rename a file or directory. The destination must not already exist.</description>
+</function>
+
+{{end}}{{if .HasTool "copy_file"}}<function name="copy_file">
+<parameter name="source"/>
+<parameter name="destination"/>
+<description>Copy a file. The destination must not already exist.</description>
+</function>
+
+{{end}}{{if .HasTool "delete_file"}}<function name="delete_file">
+<parameter name="filename"/>
+<description>Delete a file or an empty directory.</description>
+</function>
+
+{{end}}{{if .HasTool "list_files"}}<function name="list_files">
 <parameter name="pattern"/>
 <description>List files in the project. The list of files can be filtered by providing a regular expression to this function. This is equivalent to running "rg --files | rg $pattern"</description>
 </function>
 
-<function name="rg">
+{{end}}{{if .HasTool "rg"}}<function name="rg">
 <parameter name="pattern"/>
 <parameter name="directory"/>
-<description>rg (ripgrep) is a tool for recursively searching for lines matching a regex pattern.</description>
+<parameter name="glob"/>
+<parameter name="type"/>
+<parameter name="context"/>
+<parameter name="ignore_case"/>
+<parameter name="fixed_strings"/>
+<parameter name="max_matches"/>
+<description>rg (ripgrep) is a tool for recursively searching for lines matching a regex pattern. Only pattern is required. directory may be a directory or a single file. glob is a whitespace separated list of globs to include files (prefix with ! to exclude), for example "*.go !*_test.go". type is a whitespace separated list of file types such as go, py, js or ts. context is the number of lines to show before and after each match. Set ignore_case to true for case-insensitive search, and fixed_strings to true to treat the pattern as a literal string. Output lines have the form path:line:text for matches and path-line-text for context. At most max_matches matches (default 200) are returned, followed by a summary of the total when results are truncated.</description>
+</function>
+
+{{end}}{{if .HasTool "tree"}}<function name="tree">
+<parameter name="directory"/>
+<parameter name="depth"/>
+<description>Show the directory tree of a directory (default the project root), skipping hidden and ignored files. Each directory is annotated with the number of files it contains and their total size. depth is the number of levels to expand (default 3). Large directories are collapsed into a summary line.</description>
 </function>
 
-<function name="cat">
+{{end}}{{if .HasTool "cat"}}<function name="cat">
 <parameter name="filename"/>
-<description>Read the contents of a file</description>
+<parameter name="start_line"/>
+<parameter name="end_line"/>
+<description>Read the contents of a file. Each line of output is prefixed with its line number and a tab; the line numbers are not part of the file. start_line and end_line are optional 1-indexed, inclusive bounds. Large files are truncated, and the output reports the total line count so you can page through the rest with start_line and end_line.</description>
+</function>
+
+{{end}}{{if .HasTool "go_definition"}}<function name="go_definition">
+<parameter name="symbol"/>
+<description>Find where a Go symbol is defined using type information. symbol is either a dotted name such as "pkg.Func", "pkg.Type", "pkg.Type.Method" or "Type.Field", or a position "path/file.go:line:column" of an identifier. Returns the location and the source of the declaration.</description>
 </function>
 
-IMPORTANT: When calling functions, you must follow this exact format:
+{{end}}{{if .HasTool "go_references"}}<function name="go_references">
+<parameter name="symbol"/>
+<description>Find every reference to a Go symbol across the module using type information, so identically named symbols in other packages are not included. symbol has the same format as for go_definition. Returns file:line:column locations with the source line.</description>
+</function>
+
+{{end}}{{if .HasTool "go_implementations"}}<function name="go_implementations">
+<parameter name="symbol"/>
+<description>For a Go interface, find the types that implement it; for a concrete type, find the interfaces it implements. For a method, the matching methods on those types are returned. symbol has the same format as for go_definition.</description>
+</function>
+
+{{end}}{{if .HasTool "go_test"}}<function name="go_test">
+<parameter name="packages"/>
+<parameter name="run"/>
+<description>Run Go tests with "go test -json" and return a summary: pass/fail/skip counts, build errors, and for each failing test its file:line locations and output. packages is a whitespace separated list of package patterns (default ./...). run is an optional regular expression passed to -run to select tests. Long output is truncated.</description>
+</function>
+
+{{end}}{{if .HasTool "delegate"}}<function name="delegate">
+<parameter name="task"/>
+<description>Hand a research task to another assistant, such as finding where something is implemented or how a package is used. It starts with no context besides task, so describe exactly what to find and what to report. It can read and search files but not change them. Only its final summary is returned, which keeps the files it reads out of this conversation; prefer it to a long series of cat and rg calls when you need a conclusion rather than the code itself.</description>
+</function>
+
+{{end}}{{range .MCPTools}}{{if $.HasTool .Name}}<function name="{{.Name}}">
+{{range .Params}}<parameter name="{{.}}"/>
+{{end}}<description>{{.Description}}</description>
+</function>
+
+{{end}}{{end}}IMPORTANT: When calling functions, you must follow this exact format:
 
 1. Each directive must start with #{{.FunctionCallPrefix}} at the beginning of a new line
 2. Every parameter must be terminated with end_parameter
@@ -186,12 +336,14 @@ The following validation rules must be followed:
 2. The function must have end_function
 3. Must end with invoke
 4. All directives must be properly aligned at the start of a line
-{{end}}
+{{end}}`
 
-<additional rules>
+var additionalRulesTmpl = `<additional rules>
 Files should aways end with a trailing newline.
-</additional rules>
-`
+{{- if and .IncludeFSTools .PostEditChecks.Enabled}}
+When you modify Go files the function result may include post-edit checks such as formatting changes or compile and vet errors. Fix any reported errors before moving on.
+{{- end}}
+</additional rules>`
 
 var fileContentsTmpl = `{{- range .FilesContent}}
 <file>
@@ -201,6 +353,18 @@ var fileContentsTmpl = `{{- range .FilesContent}}
 {{end -}}
 `
 
+var instructionsTmpl = `{{- if .Instructions}}
+<project_instructions>
+The user and the project's maintainers provided the following instructions. Follow them. When instructions conflict, those from a more specific directory take precedence.
+{{range .Instructions}}
+<instructions path="{{.Path}}" scope="{{.Scope}}">
+{{.Content}}
+</instructions>
+{{end -}}
+</project_instructions>
+{{end -}}
+`
+
 func reverseString(input string) string {
 	rune := make([]rune, len(input))
 
@@ -30,6 +30,7 @@ func TestSystemPromptBuilderString(t *testing.T) {
 				"<function name=\"write_file\">",
 				"<function name=\"append_to_file\">",
 				"<function name=\"replace_string_in_file\">",
+				"<function name=\"apply_patch\">",
 				"<function name=\"list_files\">",
 				"<function name=\"rg\">",
 				"<function name=\"cat\">",
@@ -37,6 +38,7 @@ func TestSystemPromptBuilderString(t *testing.T) {
 			unexpected: []string{
 				"file_count=-1",
 				"#function_call,function,$FUNCTION_NAME",
+				"post-edit checks",
 			},
 		},
 		{
@@ -62,9 +64,28 @@ func TestSystemPromptBuilderString(t *testing.T) {
 			},
 		},
 		{
-			name: "Builder with FilesContent",
+			name: "Builder with ProjectTree",
 			builder: func() *SystemPromptBuilder {
 				b := newSystemPromptBuilder("test-project", "")
+				b.FileCount = 2
+				b.FirstFilesInProject = []string{"file1.go", "file2.go"}
+				b.ProjectTree = "./ (2 files, 20 B)\n├── file1.go (10 B)\n└── file2.go (10 B)\n"
+				return b
+			},
+			expected: []string{
+				"project structure",
+				"├── file1.go (10 B)",
+				"file_count=2",
+			},
+			unexpected: []string{
+				"first 10 files in project:",
+			},
+		},
+		{
+			name: "Builder with FilesContent and tools disabled",
+			builder: func() *SystemPromptBuilder {
+				b := newSystemPromptBuilder("test-project", "")
+				b.DisableTools = true
 				b.FileCount = 3
 				b.FirstFilesInProject = []string{"file1.go", "file2.go", "file3.go"}
 				b.FunctionCallPrefix = "overlapped-acknowledges"
@@ -98,6 +119,26 @@ func TestSystemPromptBuilderString(t *testing.T) {
 				"<function name=\"cat\">",
 			},
 		},
+		{
+			name: "Builder with FilesContent and tools enabled",
+			builder: func() *SystemPromptBuilder {
+				b := newSystemPromptBuilder("test-project", "")
+				b.FileCount = 3
+				b.FunctionCallPrefix = "overlapped-acknowledges"
+				b.FilesContent = []FileContent{
+					{FileName: "file1.go", Content: "package main"},
+				}
+				b.PostEditChecks.Vet = true
+				return b
+			},
+			expected: []string{
+				"<filename>file1.go</filename>",
+				"project=test-project",
+				"#overlapped-acknowledges,function,$FUNCTION_NAME",
+				"<function name=\"cat\">",
+				"post-edit checks",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,360 @@
+package interactive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/psanford/claude/anthropic"
+)
+
+const (
+	defaultReviewMaxTurns = 30
+	// limits on the file contents included with the diff; the model can
+	// read anything left out with cat
+	reviewMaxFileBytes  = 50000
+	reviewMaxTotalBytes = 200000
+)
+
+// Review severities, most severe first.
+var ReviewSeverities = []string{"error", "warning", "suggestion", "nit"}
+
+type ReviewComment struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+type Review struct {
+	Range    string          `json:"range"`
+	Summary  string          `json:"summary"`
+	Comments []ReviewComment `json:"comments"`
+}
+
+type ReviewOptions struct {
+	// Range is "<base>..<head>", "<base>...<head>" or a single base
+	// revision compared with HEAD. Empty reviews uncommitted changes.
+	Range    string
+	MaxTurns int
+	// Log receives the tools the model runs, if set.
+	Log io.Writer
+}
+
+// reviewTarget describes what is being reviewed.
+type reviewTarget struct {
+	label    string
+	diffArgs []string
+	// head is the revision to read file contents from; empty means the
+	// working tree
+	head string
+}
+
+// parseReviewRange interprets rangeArg the way git diff does: "a..b"
+// compares a with b, "a...b" compares b with the point where it diverged
+// from a, and a single revision is compared with the working tree. An
+// empty range reviews uncommitted changes.
+func parseReviewRange(rangeArg string) reviewTarget {
+	if rangeArg == "" {
+		return reviewTarget{label: "uncommitted changes", diffArgs: []string{"HEAD"}}
+	}
+
+	target := reviewTarget{label: rangeArg, diffArgs: []string{rangeArg}}
+	_, head, ok := strings.Cut(rangeArg, "...")
+	if !ok {
+		_, head, ok = strings.Cut(rangeArg, "..")
+	}
+	if ok {
+		if head == "" {
+			head = "HEAD"
+		}
+		target.head = head
+	}
+	return target
+}
+
+// untrackedDiff returns a diff adding each untracked file that isn't
+// ignored, which "git diff HEAD" leaves out, and their paths relative to
+// the repository root.
+func untrackedDiff() ([]byte, []string, error) {
+	root := gitRoot(".")
+	out, err := gitOutput("-C", root, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		diff  bytes.Buffer
+		names = splitNul(out)
+	)
+	for _, name := range names {
+		cmd := exec.Command("git", "-C", root, "diff", "--no-index", "--", os.DevNull, name)
+		d, err := cmd.Output()
+		// exit status 1 means the files differ, which they always do
+		var exitErr *exec.ExitError
+		if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+			return nil, nil, fmt.Errorf("git diff --no-index %s: %w", name, err)
+		}
+		diff.Write(d)
+	}
+	return diff.Bytes(), names, nil
+}
+
+// splitNul splits the output of a git command run with -z.
+func splitNul(out []byte) []string {
+	var names []string
+	for _, name := range strings.Split(string(out), "\x00") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Review asks the model to review a diff. The model may use the read-only
+// tools to look at the rest of the project, and never gets tools that
+// modify files.
+func (r *Runner) Review(ctx context.Context, opts ReviewOptions) (*Review, error) {
+	target := parseReviewRange(opts.Range)
+
+	// "--" keeps the range from being read as a path or an option
+	diff, err := gitOutput(append(append([]string{"diff"}, target.diffArgs...), "--")...)
+	if err != nil {
+		return nil, err
+	}
+	out, err := gitOutput(append(append([]string{"diff", "--name-only", "-z", "--diff-filter=d"}, target.diffArgs...), "--")...)
+	if err != nil {
+		return nil, err
+	}
+	names := splitNul(out)
+
+	if target.head == "" {
+		untracked, untrackedNames, err := untrackedDiff()
+		if err != nil {
+			return nil, err
+		}
+		diff = append(diff, untracked...)
+		names = append(names, untrackedNames...)
+	}
+	if len(bytes.TrimSpace(diff)) == 0 {
+		return nil, fmt.Errorf("no changes to review in %s", target.label)
+	}
+
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Review the following change (%s).\n\n", target.label)
+	if target.head != "" {
+		prompt.WriteString("The tools read the current checkout, which may differ from the reviewed revision; the file contents below are from the reviewed revision.\n\n")
+	}
+	fmt.Fprintf(&prompt, "<diff>\n%s</diff>\n\n", diff)
+	writeReviewFiles(&prompt, target, names)
+
+	b := newSystemPromptBuilder(inferProject(), reviewPromptTemplate)
+	b.Tools = readOnlyTools
+	if b.Instructions, err = findInstructions("."); err != nil {
+		return nil, err
+	}
+	system, err := b.Render()
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := toolRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	maxTurns := opts.MaxTurns
+	if maxTurns <= 0 {
+		maxTurns = defaultReviewMaxTurns
+	}
+
+	a := &agent{
+		runner:      r,
+		client:      anthropic.NewClient(r.APIKey, anthropic.WithDebugLogger(r.DebugLogger)),
+		model:       r.apiModel(),
+		system:      system,
+		tools:       readOnlyTools,
+		root:        root,
+		maxTurns:    maxTurns,
+		log:         opts.Log,
+		debugLogger: r.DebugLogger,
+	}
+	reply, err := a.run(ctx, prompt.String())
+	if err != nil {
+		return nil, err
+	}
+
+	review, err := parseReview(reply)
+	if err != nil {
+		return nil, err
+	}
+	review.Range = target.label
+	return review, nil
+}
+
+// writeReviewFiles adds the full contents of the changed files so the
+// model sees each change in context.
+func writeReviewFiles(w *strings.Builder, target reviewTarget, files []string) {
+	var (
+		total   int
+		skipped []string
+		root    = gitRoot(".")
+	)
+	for _, name := range files {
+		var (
+			content []byte
+			err     error
+		)
+		if target.head == "" {
+			// git diff prints paths relative to the repository root
+			content, err = os.ReadFile(filepath.Join(root, filepath.FromSlash(name)))
+		} else {
+			content, err = gitOutput("show", target.head+":"+name)
+		}
+		if err != nil || bytes.IndexByte(content, 0) >= 0 {
+			continue
+		}
+		if len(content) > reviewMaxFileBytes || total+len(content) > reviewMaxTotalBytes {
+			skipped = append(skipped, name)
+			continue
+		}
+		total += len(content)
+		fmt.Fprintf(w, "<file>\n<filename>%s</filename>\n<filecontent>%s</filecontent>\n</file>\n", name, content)
+	}
+	if len(skipped) > 0 {
+		fmt.Fprintf(w, "\nThese changed files are too large to include; read them with cat if needed: %s\n", strings.Join(skipped, ", "))
+	}
+}
+
+// parseReview extracts the JSON review from the model's final reply. If
+// the reply has no valid review block the whole reply is used as the
+// summary.
+func parseReview(reply string) (*Review, error) {
+	start := strings.LastIndex(reply, "<review>")
+	end := strings.LastIndex(reply, "</review>")
+	if start < 0 || end < start {
+		if strings.TrimSpace(reply) == "" {
+			return nil, errors.New("model returned an empty review")
+		}
+		return &Review{Summary: strings.TrimSpace(reply)}, nil
+	}
+
+	var review Review
+	body := strings.TrimSpace(reply[start+len("<review>") : end])
+	body = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(body, "```json"), "```"), "```")
+	if err := json.Unmarshal([]byte(body), &review); err != nil {
+		return nil, fmt.Errorf("parse review: %w", err)
+	}
+
+	for i, c := range review.Comments {
+		sev := strings.ToLower(strings.TrimSpace(c.Severity))
+		if severityRank(sev) < 0 {
+			sev = "suggestion"
+		}
+		review.Comments[i].Severity = sev
+	}
+	sort.SliceStable(review.Comments, func(i, j int) bool {
+		ci, cj := review.Comments[i], review.Comments[j]
+		if ci.File != cj.File {
+			return ci.File < cj.File
+		}
+		return ci.Line < cj.Line
+	})
+	return &review, nil
+}
+
+// severityRank returns the index of sev in ReviewSeverities, or -1.
+func severityRank(sev string) int {
+	for i, s := range ReviewSeverities {
+		if s == sev {
+			return i
+		}
+	}
+	return -1
+}
+
+// HasSeverity reports whether any comment is at least as severe as sev.
+func (r *Review) HasSeverity(sev string) bool {
+	limit := severityRank(sev)
+	for _, c := range r.Comments {
+		if rank := severityRank(c.Severity); rank >= 0 && rank <= limit {
+			return true
+		}
+	}
+	return false
+}
+
+var severityColors = map[string]string{
+	"error":      "\x1b[31m",
+	"warning":    "\x1b[33m",
+	"suggestion": "\x1b[36m",
+	"nit":        "\x1b[2m",
+}
+
+// WriteText writes the review for a terminal, one file:line: comment per
+// line so editors can jump to them.
+func (r *Review) WriteText(w io.Writer, color bool) {
+	fmt.Fprintf(w, "Review of %s\n\n%s\n", r.Range, strings.TrimSpace(r.Summary))
+	if len(r.Comments) == 0 {
+		fmt.Fprintln(w, "\nNo issues found.")
+		return
+	}
+	fmt.Fprintln(w)
+	for _, c := range r.Comments {
+		sev := c.Severity
+		if color {
+			sev = severityColors[sev] + sev + "\x1b[0m"
+		}
+		fmt.Fprintf(w, "%s:%d: %s: %s\n", c.File, c.Line, sev, strings.TrimSpace(c.Message))
+	}
+}
+
+// WriteGitHub writes the comments as GitHub Actions workflow commands,
+// which show up as annotations on the pull request.
+func (r *Review) WriteGitHub(w io.Writer) {
+	level := map[string]string{
+		"error":      "error",
+		"warning":    "warning",
+		"suggestion": "notice",
+		"nit":        "notice",
+	}
+	for _, c := range r.Comments {
+		fmt.Fprintf(w, "::%s file=%s,line=%d,title=%s::%s\n",
+			level[c.Severity], escapeGitHubProperty(c.File), c.Line, escapeGitHubProperty("code-buddy "+c.Severity), escapeGitHubData(c.Message))
+	}
+	fmt.Fprintf(w, "::notice title=code-buddy review::%s\n", escapeGitHubData(r.Summary))
+}
+
+func escapeGitHubData(s string) string {
+	s = strings.ReplaceAll(s, "%", "%25")
+	s = strings.ReplaceAll(s, "\r", "%0D")
+	return strings.ReplaceAll(s, "\n", "%0A")
+}
+
+func escapeGitHubProperty(s string) string {
+	s = escapeGitHubData(s)
+	s = strings.ReplaceAll(s, ":", "%3A")
+	return strings.ReplaceAll(s, ",", "%2C")
+}
+
+var reviewPromptTemplate = `You are an experienced software engineer reviewing a code change. Look for bugs, security problems, race conditions, incorrect error handling, API misuse, missing tests and confusing code. Use the tools to read related code, such as the callers of changed functions or the implementations of changed interfaces, whenever that helps you decide whether the change is correct. Do not comment on formatting that tools such as gofmt handle, and only report problems you are confident about.
+
+{{template "context" .}}
+
+{{template "tools" .}}
+
+<review_format>
+When you have finished, reply with the review as a JSON object inside <review></review> tags, with nothing after it:
+<review>
+{"summary": "One paragraph overall assessment.", "comments": [{"file": "path/to/file.go", "line": 42, "severity": "error", "message": "What is wrong and how to fix it."}]}
+</review>
+severity is one of error (a bug that must be fixed), warning (a likely problem), suggestion (an improvement) or nit (a minor issue). file is relative to the repository root and line is a line number in the new version of the file. Use an empty comments list if you found no problems.
+</review_format>
+`
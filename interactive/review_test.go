@@ -0,0 +1,120 @@
+package interactive
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseReviewRange(t *testing.T) {
+	tests := []struct {
+		arg   string
+		label string
+		head  string
+	}{
+		{"", "uncommitted changes", ""},
+		{"main", "main", ""},
+		{"main..feature", "main..feature", "feature"},
+		{"main...feature", "main...feature", "feature"},
+		{"main..", "main..", "HEAD"},
+	}
+	for _, tc := range tests {
+		got := parseReviewRange(tc.arg)
+		if got.label != tc.label || got.head != tc.head || (tc.arg != "" && got.diffArgs[0] != tc.arg) {
+			t.Errorf("parseReviewRange(%q) = %+v", tc.arg, got)
+		}
+	}
+}
+
+func TestParseReview(t *testing.T) {
+	reply := `I looked at the callers.
+
+<review>
+{"summary": "Mostly fine.", "comments": [
+  {"file": "b.go", "line": 3, "severity": "Nit", "message": "rename"},
+  {"file": "a.go", "line": 10, "severity": "error", "message": "nil deref,\nwhen x is empty"},
+  {"file": "a.go", "line": 2, "severity": "critical", "message": "odd"}
+]}
+</review>`
+
+	review, err := parseReview(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	review.Range = "main...HEAD"
+
+	var got []string
+	for _, c := range review.Comments {
+		got = append(got, c.File+":"+c.Severity)
+	}
+	if strings.Join(got, " ") != "a.go:suggestion a.go:error b.go:nit" {
+		t.Fatalf("comments: %v", got)
+	}
+
+	if !review.HasSeverity("error") || !review.HasSeverity("warning") {
+		t.Errorf("expected error severity to count for error and warning")
+	}
+	review.Comments = review.Comments[2:]
+	if review.HasSeverity("suggestion") {
+		t.Errorf("nit should not count as a suggestion")
+	}
+
+	var buf strings.Builder
+	review.Comments = []ReviewComment{{File: "a,b.go", Line: 10, Severity: "error", Message: "100% wrong\nreally"}}
+	review.WriteGitHub(&buf)
+	want := "::error file=a%2Cb.go,line=10,title=code-buddy error::100%25 wrong%0Areally\n::notice title=code-buddy review::Mostly fine.\n"
+	if buf.String() != want {
+		t.Errorf("github output:\n%q\nwant:\n%q", buf.String(), want)
+	}
+
+	review, err = parseReview("No structured output here.")
+	if err != nil || review.Summary != "No structured output here." || len(review.Comments) != 0 {
+		t.Errorf("fallback review: %+v %v", review, err)
+	}
+}
+
+func TestReviewPromptTools(t *testing.T) {
+	b := newSystemPromptBuilder("test-project", reviewPromptTemplate)
+	b.Tools = readOnlyTools
+	prompt := b.String()
+	for _, want := range []string{`<function name="cat">`, `<function name="rg">`, `<function name="go_references">`, "<review_format>"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("review prompt missing %q", want)
+		}
+	}
+	for _, unwanted := range []string{`<function name="write_file">`, `<function name="apply_patch">`, `<function name="delete_file">`, `<function name="go_test">`} {
+		if strings.Contains(prompt, unwanted) {
+			t.Errorf("review prompt should not offer %q", unwanted)
+		}
+	}
+}
+
+func TestUntrackedDiff(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	chdir(t, dir)
+
+	if out, err := exec.Command("git", "init", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %s %s", err, out)
+	}
+	os.WriteFile(".gitignore", []byte("ignored.txt\n"), 0644)
+	os.WriteFile("ignored.txt", []byte("x\n"), 0644)
+	os.Mkdir("sub", 0755)
+	os.WriteFile(filepath.Join("sub", "new file.txt"), []byte("hello\n"), 0644)
+	chdir(t, "sub")
+
+	diff, names, err := untrackedDiff()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(names, ",") != ".gitignore,sub/new file.txt" {
+		t.Errorf("names = %q", names)
+	}
+	if !strings.Contains(string(diff), "+hello") || strings.Contains(string(diff), "ignored.txt\n+x") {
+		t.Errorf("diff = %s", diff)
+	}
+}
@@ -0,0 +1,65 @@
+package interactive
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// shellOutputMaxLines limits how much of a ! command's output is added to
+// the conversation. The user always sees all of it.
+const shellOutputMaxLines = 400
+
+// shellResult is the output of a command run with the ! prefix.
+type shellResult struct {
+	command  string
+	output   string
+	exitCode int
+}
+
+// runShellCommand runs command with the user's shell, streaming its
+// combined output to out as well as capturing it.
+func runShellCommand(command string, out io.Writer) shellResult {
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+
+	var buf strings.Builder
+	cmd := exec.Command(shell, "-c", command)
+	cmd.Stdout = io.MultiWriter(out, &buf)
+	cmd.Stderr = cmd.Stdout
+
+	res := shellResult{command: command}
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		res.exitCode = exitErr.ExitCode()
+	} else if err != nil {
+		fmt.Fprintf(&buf, "%s\n", err)
+		fmt.Fprintf(out, "%s\n", err)
+		res.exitCode = -1
+	}
+	res.output = buf.String()
+	return res
+}
+
+// shellContext formats command results to send along with the next user
+// message. Long output keeps its first and last lines.
+func shellContext(results []shellResult) string {
+	var buf strings.Builder
+	for _, res := range results {
+		lines := strings.Split(strings.TrimRight(res.output, "\n"), "\n")
+		if len(lines) > shellOutputMaxLines {
+			head := shellOutputMaxLines / 2
+			tail := shellOutputMaxLines - head
+			omitted := len(lines) - shellOutputMaxLines
+			lines = append(append(lines[:head:head], fmt.Sprintf("[… %d lines omitted …]", omitted)), lines[len(lines)-tail:]...)
+		}
+		fmt.Fprintf(&buf, "<shell_command>\n<command>%s</command>\n<output>\n%s\n</output>\n<exit_code>%d</exit_code>\n</shell_command>\n", res.command, strings.Join(lines, "\n"), res.exitCode)
+	}
+	return buf.String()
+}
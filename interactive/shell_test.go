@@ -0,0 +1,46 @@
+package interactive
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRunShellCommand(t *testing.T) {
+	t.Setenv("SHELL", "/bin/sh")
+
+	var out strings.Builder
+	res := runShellCommand("echo hello; echo oops >&2; exit 3", &out)
+	if res.exitCode != 3 {
+		t.Errorf("exitCode = %d, want 3", res.exitCode)
+	}
+	if res.output != "hello\noops\n" {
+		t.Errorf("output = %q", res.output)
+	}
+	if out.String() != res.output {
+		t.Errorf("streamed output = %q, want %q", out.String(), res.output)
+	}
+
+	got := shellContext([]shellResult{res})
+	want := "<shell_command>\n<command>echo hello; echo oops >&2; exit 3</command>\n<output>\nhello\noops\n</output>\n<exit_code>3</exit_code>\n</shell_command>\n"
+	if got != want {
+		t.Errorf("shellContext =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestShellContextCapped(t *testing.T) {
+	var lines []string
+	for i := 0; i < shellOutputMaxLines+100; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	got := shellContext([]shellResult{{command: "seq", output: strings.Join(lines, "\n") + "\n"}})
+
+	for _, s := range []string{"line 0\n", "[… 100 lines omitted …]", fmt.Sprintf("line %d\n", shellOutputMaxLines+99)} {
+		if !strings.Contains(got, s) {
+			t.Errorf("expected %q in output", s)
+		}
+	}
+	if strings.Contains(got, fmt.Sprintf("line %d\n", shellOutputMaxLines/2)) {
+		t.Errorf("expected middle lines to be omitted")
+	}
+}
@@ -0,0 +1,151 @@
+package interactive
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/psanford/code-buddy/config"
+)
+
+// projectCommandsDir is where a project keeps its slash commands, relative
+// to the repository root.
+const projectCommandsDir = ".code-buddy/commands"
+
+// builtinCommands can't be replaced by user-defined commands.
+var builtinCommands = []string{
+	"help", "reset", "multiline", "model", "system", "history", "info",
+	"add", "drop", "files", "tools", "mcp", "memory", "refresh", "commit", "git-undo", "quit",
+}
+
+// slashCommands returns the user-defined commands. Commands in the
+// project's .code-buddy/commands directory take precedence over ones from
+// the config file, which take precedence over files in the user's
+// commands directory.
+func (r *Runner) slashCommands() []config.SlashCommand {
+	var (
+		commands []config.SlashCommand
+		seen     = make(map[string]bool)
+	)
+	for _, name := range builtinCommands {
+		seen[name] = true
+	}
+	add := func(cmds []config.SlashCommand) {
+		for _, c := range cmds {
+			if c.Name == "" || seen[c.Name] {
+				continue
+			}
+			seen[c.Name] = true
+			commands = append(commands, c)
+		}
+	}
+
+	projectRoot := gitRoot(".")
+	if projectRoot == "" {
+		projectRoot = "."
+	}
+	add(loadCommandsDir(filepath.Join(projectRoot, filepath.FromSlash(projectCommandsDir))))
+	add(r.Commands)
+	if dir := config.CommandsDir(); dir != "" {
+		add(loadCommandsDir(dir))
+	}
+
+	sort.Slice(commands, func(i, j int) bool {
+		return commands[i].Name < commands[j].Name
+	})
+	return commands
+}
+
+func (r *Runner) slashCommandNames() []string {
+	var names []string
+	for _, c := range r.slashCommands() {
+		names = append(names, "/"+c.Name)
+	}
+	return names
+}
+
+// findSlashCommand returns the user-defined command called name. Files are
+// read on every call so edits take effect immediately.
+func (r *Runner) findSlashCommand(name string) (config.SlashCommand, bool) {
+	for _, c := range r.slashCommands() {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return config.SlashCommand{}, false
+}
+
+// loadCommandsDir reads the *.md files in dir as slash commands.
+func loadCommandsDir(dir string) []config.SlashCommand {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var commands []config.SlashCommand
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".md" {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			fmt.Printf("read command %s err: %s\n", e.Name(), err)
+			continue
+		}
+		c := parseCommandFile(string(content))
+		c.Name = strings.TrimSuffix(e.Name(), ".md")
+		commands = append(commands, c)
+	}
+	return commands
+}
+
+// parseCommandFile parses a markdown command file. The file may start with
+// a front matter block setting the description and allowed tools:
+//
+//	---
+//	description: Explain the staged changes
+//	allowed-tools: cat, rg, list_files
+//	---
+//	Explain what the staged changes do. $ARGUMENTS
+func parseCommandFile(content string) config.SlashCommand {
+	var c config.SlashCommand
+	body := content
+	if rest, ok := strings.CutPrefix(content, "---\n"); ok {
+		if header, after, ok := strings.Cut(rest, "\n---\n"); ok {
+			body = after
+			for _, line := range strings.Split(header, "\n") {
+				key, value, ok := strings.Cut(line, ":")
+				if !ok {
+					continue
+				}
+				value = strings.TrimSpace(value)
+				switch strings.TrimSpace(key) {
+				case "description":
+					c.Description = value
+				case "allowed-tools", "allowed_tools":
+					for _, tool := range strings.Split(value, ",") {
+						if tool = strings.TrimSpace(tool); tool != "" {
+							c.AllowedTools = append(c.AllowedTools, tool)
+						}
+					}
+				}
+			}
+		}
+	}
+	c.Prompt = strings.TrimSpace(body)
+	return c
+}
+
+// expandSlashCommand returns the prompt for c invoked with args. If the
+// prompt doesn't use $ARGUMENTS the arguments are appended to it.
+func expandSlashCommand(c config.SlashCommand, args string) string {
+	args = strings.TrimSpace(args)
+	if strings.Contains(c.Prompt, "$ARGUMENTS") {
+		return strings.ReplaceAll(c.Prompt, "$ARGUMENTS", args)
+	}
+	if args == "" {
+		return c.Prompt
+	}
+	return c.Prompt + "\n\n" + args
+}
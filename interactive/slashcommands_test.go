@@ -0,0 +1,125 @@
+package interactive
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/psanford/code-buddy/config"
+)
+
+func TestParseCommandFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    config.SlashCommand
+	}{
+		{
+			name:    "plain prompt",
+			content: "Explain $ARGUMENTS\n",
+			want:    config.SlashCommand{Prompt: "Explain $ARGUMENTS"},
+		},
+		{
+			name:    "front matter",
+			content: "---\ndescription: Explain code\nallowed-tools: cat, rg,list_files\n---\n\nExplain $ARGUMENTS\n",
+			want: config.SlashCommand{
+				Description:  "Explain code",
+				Prompt:       "Explain $ARGUMENTS",
+				AllowedTools: []string{"cat", "rg", "list_files"},
+			},
+		},
+		{
+			name:    "unterminated front matter",
+			content: "---\ndescription: oops\n",
+			want:    config.SlashCommand{Prompt: "---\ndescription: oops"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseCommandFile(tt.content)
+			if got.Description != tt.want.Description || got.Prompt != tt.want.Prompt || !slices.Equal(got.AllowedTools, tt.want.AllowedTools) {
+				t.Errorf("parseCommandFile = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandSlashCommand(t *testing.T) {
+	tests := []struct {
+		prompt, args, want string
+	}{
+		{"Fix issue $ARGUMENTS. Then test $ARGUMENTS.", " 42 ", "Fix issue 42. Then test 42."},
+		{"Review the staged changes", "focus on errors", "Review the staged changes\n\nfocus on errors"},
+		{"Review the staged changes", "", "Review the staged changes"},
+		{"Explain $ARGUMENTS", "", "Explain "},
+	}
+	for _, tt := range tests {
+		got := expandSlashCommand(config.SlashCommand{Prompt: tt.prompt}, tt.args)
+		if got != tt.want {
+			t.Errorf("expandSlashCommand(%q, %q) = %q, want %q", tt.prompt, tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestSlashCommands(t *testing.T) {
+	confDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", confDir)
+	t.Setenv("HOME", confDir)
+
+	dir := t.TempDir()
+	chdir(t, dir)
+
+	writeFile := func(p, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	userDir := config.CommandsDir()
+	writeFile(filepath.Join(userDir, "explain.md"), "user explain")
+	writeFile(filepath.Join(userDir, "todo.md"), "user todo")
+	writeFile(filepath.Join(userDir, "notes.txt"), "not a command")
+	writeFile(filepath.Join(dir, projectCommandsDir, "explain.md"), "project explain")
+	writeFile(filepath.Join(dir, projectCommandsDir, "help.md"), "can't replace /help")
+
+	r := &Runner{
+		Commands: []config.SlashCommand{
+			{Name: "todo", Prompt: "config todo"},
+			{Name: "fix", Prompt: "config fix", AllowedTools: []string{"cat"}},
+		},
+	}
+
+	want := map[string]string{
+		"explain": "project explain",
+		"fix":     "config fix",
+		"todo":    "config todo",
+	}
+	got := make(map[string]string)
+	for _, c := range r.slashCommands() {
+		got[c.Name] = c.Prompt
+	}
+	if len(got) != len(want) {
+		t.Fatalf("slashCommands = %v, want %v", got, want)
+	}
+	for name, prompt := range want {
+		if got[name] != prompt {
+			t.Errorf("command %s = %q, want %q", name, got[name], prompt)
+		}
+	}
+
+	if names, want := r.slashCommandNames(), []string{"/explain", "/fix", "/todo"}; !slices.Equal(names, want) {
+		t.Errorf("slashCommandNames = %q, want %q", names, want)
+	}
+
+	if _, ok := r.findSlashCommand("help"); ok {
+		t.Errorf("found a user-defined /help")
+	}
+	if c, ok := r.findSlashCommand("fix"); !ok || !slices.Equal(c.AllowedTools, []string{"cat"}) {
+		t.Errorf("findSlashCommand(fix) = %+v, %t", c, ok)
+	}
+}
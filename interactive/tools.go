@@ -0,0 +1,189 @@
+package interactive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var (
+	errUnknownTool   = errors.New("unknown tool")
+	errToolsDisabled = errors.New("tools are disabled")
+)
+
+// readOnlyTools are the tools that never modify files. They are the only
+// tools offered in review mode and to delegated sub-agents, which run them
+// without asking. The go_* tools load packages with go/packages, which runs
+// "go list": that may download the Go toolchain named in go.mod and run
+// cgo and pkg-config for packages that use cgo.
+var readOnlyTools = []string{
+	"list_files",
+	"rg",
+	"tree",
+	"cat",
+	"go_definition",
+	"go_references",
+	"go_implementations",
+}
+
+// contextCmd is implemented by commands that can be cancelled, such as
+// delegate, which makes model requests of its own.
+type contextCmd interface {
+	RunContext(ctx context.Context) (string, error)
+}
+
+// runCmd runs cmd, passing ctx to commands that accept one.
+func runCmd(ctx context.Context, cmd Cmd) (string, error) {
+	if c, ok := cmd.(contextCmd); ok {
+		return c.RunContext(ctx)
+	}
+	return cmd.Run()
+}
+
+// toolRoot returns the directory unattended tools are confined to: the
+// root of the git repository containing the current directory, or the
+// current directory outside a repository.
+func toolRoot() (string, error) {
+	root := gitRoot(".")
+	if root == "" {
+		var err error
+		if root, err = os.Getwd(); err != nil {
+			return "", err
+		}
+	}
+	return filepath.EvalSymlinks(root)
+}
+
+// toolAllowed reports whether name is in tools; a nil list allows every tool.
+func toolAllowed(tools []string, name string) bool {
+	return tools == nil || slices.Contains(tools, name)
+}
+
+// toolCmd builds the command for a function call in the interactive
+// session. Calls are rejected while tools are disabled, since the model can
+// still copy earlier calls from its history, and when the current slash
+// command restricts the tools to requestTools.
+func (r *Runner) toolCmd(name string, paramMap map[string]string, requestTools []string) (Cmd, error) {
+	if r.DisableTools {
+		return nil, errToolsDisabled
+	}
+	if !toolAllowed(requestTools, name) {
+		return nil, fmt.Errorf("tool %s is not available for this command", name)
+	}
+	return r.newCmd(name, paramMap)
+}
+
+// newCmd builds the command for a function call from the model.
+func (r *Runner) newCmd(name string, paramMap map[string]string) (Cmd, error) {
+	var (
+		cmd    Cmd
+		cmdErr error
+	)
+
+	switch name {
+	case "list_files":
+		cmd = &ListFilesArgs{
+			Pattern: paramMap["pattern"],
+		}
+	case "rg":
+		cmd, cmdErr = newRGArgs(paramMap)
+	case "tree":
+		treeArgs := &TreeArgs{
+			Directory: strings.TrimSpace(paramMap["directory"]),
+		}
+		treeArgs.Depth, cmdErr = intParam(paramMap, "depth")
+		cmd = treeArgs
+	case "cat":
+		catArgs := &CatArgs{
+			Filename: paramMap["filename"],
+			MaxLines: r.CatMaxLines,
+		}
+		catArgs.StartLine, cmdErr = intParam(paramMap, "start_line")
+		if cmdErr == nil {
+			catArgs.EndLine, cmdErr = intParam(paramMap, "end_line")
+		}
+		cmd = catArgs
+	case "write_file":
+		cmd = &ModifyFileArgs{
+			Filename: paramMap["filename"],
+			Content:  paramMap["content"],
+		}
+	case "append_to_file":
+		cmd = &AppendToFileArgs{
+			Filename: paramMap["filename"],
+			Content:  paramMap["content"],
+		}
+	case "replace_string_in_file":
+		count := 1
+		if strings.TrimSpace(paramMap["count"]) != "" {
+			count, cmdErr = intParam(paramMap, "count")
+		}
+		cmd = &ReplaceStringInFileArgs{
+			Filename:       paramMap["filename"],
+			OriginalString: paramMap["original_string"],
+			NewString:      paramMap["new_string"],
+			Count:          count,
+		}
+	case "edit_file":
+		var edits []fileEdit
+		edits, cmdErr = parseEdits(paramMap["edits"])
+		cmd = &EditFileArgs{
+			Filename: paramMap["filename"],
+			Edits:    edits,
+		}
+	case "mkdir":
+		cmd = &MkdirArgs{
+			Path: paramMap["path"],
+		}
+	case "move_file":
+		cmd = &MoveFileArgs{
+			Source:      paramMap["source"],
+			Destination: paramMap["destination"],
+		}
+	case "copy_file":
+		cmd = &CopyFileArgs{
+			Source:      paramMap["source"],
+			Destination: paramMap["destination"],
+		}
+	case "delete_file":
+		cmd = &DeleteFileArgs{
+			Filename: paramMap["filename"],
+		}
+	case "go_definition":
+		cmd = &GoDefinitionArgs{
+			Symbol: paramMap["symbol"],
+		}
+	case "go_references":
+		cmd = &GoReferencesArgs{
+			Symbol: paramMap["symbol"],
+		}
+	case "go_implementations":
+		cmd = &GoImplementationsArgs{
+			Symbol: paramMap["symbol"],
+		}
+	case "go_test":
+		cmd = &GoTestArgs{
+			Packages: listParam(paramMap, "packages"),
+			RunRegex: strings.TrimSpace(paramMap["run"]),
+		}
+	case "apply_patch":
+		cmd = &ApplyPatchArgs{
+			Patch: paramMap["patch"],
+		}
+	case "delegate":
+		cmd = &DelegateArgs{
+			Task:   paramMap["task"],
+			runner: r,
+		}
+	default:
+		if t := r.mcpTools[name]; t != nil {
+			return newMCPToolArgs(t, paramMap)
+		}
+		return nil, fmt.Errorf("%w %s", errUnknownTool, name)
+	}
+	return cmd, cmdErr
+}
@@ -0,0 +1,32 @@
+package interactive
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestToolCmd(t *testing.T) {
+	params := map[string]string{"filename": "a.txt"}
+
+	r := &Runner{}
+	if _, err := r.toolCmd("cat", params, nil); err != nil {
+		t.Errorf("cat with all tools allowed: %v", err)
+	}
+	if _, err := r.toolCmd("cat", params, readOnlyTools); err != nil {
+		t.Errorf("cat with read-only tools: %v", err)
+	}
+	if _, err := r.toolCmd("write_file", params, readOnlyTools); err == nil || !strings.Contains(err.Error(), "not available") {
+		t.Errorf("write_file with read-only tools: err = %v", err)
+	}
+	if _, err := r.toolCmd("bogus", params, nil); !errors.Is(err, errUnknownTool) {
+		t.Errorf("unknown tool: err = %v", err)
+	}
+
+	r.DisableTools = true
+	for _, name := range []string{"cat", "write_file", "bogus"} {
+		if _, err := r.toolCmd(name, params, nil); !errors.Is(err, errToolsDisabled) {
+			t.Errorf("%s with tools disabled: err = %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+// Package textutil has small formatting helpers shared by code-buddy's
+// packages.
+package textutil
+
+import "fmt"
+
+// Plural returns singular if n is 1 and pluralForm otherwise.
+func Plural(n int, singular, pluralForm string) string {
+	if n == 1 {
+		return singular
+	}
+	return pluralForm
+}
+
+// FormatSize formats a byte count using binary units, e.g. "1.5 KB".
+func FormatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,306 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stderrTailBytes is how much of a server's stderr is kept to explain
+// failures.
+const stderrTailBytes = 4096
+
+// closeTimeout is how long a server gets to exit after its stdin is closed.
+const closeTimeout = 2 * time.Second
+
+// ServerParams describes how to launch an MCP server.
+type ServerParams struct {
+	Command string
+	Args    []string
+	// Env is added to code-buddy's own environment.
+	Env []string
+}
+
+// Client is a connection to an MCP server running as a subprocess.
+type Client struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stderr *tailBuffer
+
+	// ServerInfo and Instructions are set by the server during
+	// initialization.
+	ServerInfo   Implementation
+	Instructions string
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan *message
+	closed  bool
+	// err is why the connection ended
+	err  error
+	done chan struct{}
+}
+
+// Start launches the server and performs the MCP initialization
+// handshake.
+func Start(ctx context.Context, params ServerParams) (*Client, error) {
+	cmd := exec.Command(params.Command, params.Args...)
+	cmd.Env = append(os.Environ(), params.Env...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{
+		cmd:     cmd,
+		stdin:   stdin,
+		stderr:  &tailBuffer{max: stderrTailBytes},
+		pending: make(map[int64]chan *message),
+		done:    make(chan struct{}),
+	}
+	cmd.Stderr = c.stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	go c.readLoop(stdout)
+
+	var result initializeResult
+	err = c.call(ctx, "initialize", initializeParams{
+		ProtocolVersion: ProtocolVersion,
+		Capabilities:    map[string]any{},
+		ClientInfo:      Implementation{Name: "code-buddy", Version: "0.1"},
+	}, &result)
+	if err != nil {
+		c.Close()
+		return nil, fmt.Errorf("initialize: %w", err)
+	}
+	c.ServerInfo = result.ServerInfo
+	c.Instructions = result.Instructions
+
+	if err := c.notify("notifications/initialized", nil); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// ListTools returns every tool the server provides.
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var (
+		tools  []Tool
+		cursor string
+	)
+	for {
+		var result listToolsResult
+		if err := c.call(ctx, "tools/list", listToolsParams{Cursor: cursor}, &result); err != nil {
+			return nil, err
+		}
+		tools = append(tools, result.Tools...)
+		if result.NextCursor == "" || result.NextCursor == cursor {
+			return tools, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+// CallTool runs a tool. A tool that fails reports it with IsError in the
+// result rather than an error.
+func (c *Client) CallTool(ctx context.Context, name string, args map[string]any) (*CallToolResult, error) {
+	var result CallToolResult
+	if err := c.call(ctx, "tools/call", callToolParams{Name: name, Arguments: args}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Close stops the server.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	alreadyClosed := c.closed
+	c.closed = true
+	c.mu.Unlock()
+	if alreadyClosed {
+		return nil
+	}
+
+	// Closing stdin asks the server to exit; kill it if it doesn't. The
+	// output must be read to the end before calling Wait.
+	c.stdin.Close()
+	select {
+	case <-c.done:
+	case <-time.After(closeTimeout):
+		c.cmd.Process.Kill()
+		<-c.done
+	}
+	c.cmd.Wait()
+	return nil
+}
+
+func (c *Client) call(ctx context.Context, method string, params, result any) error {
+	c.mu.Lock()
+	if c.closed || c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		if err == nil {
+			err = errors.New("connection closed")
+		}
+		return err
+	}
+	c.nextID++
+	id := c.nextID
+	ch := make(chan *message, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	rawID := json.RawMessage(fmt.Sprint(id))
+	if err := c.send(&message{ID: &rawID, Method: method}, params); err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		c.notify("notifications/cancelled", map[string]any{"requestId": id, "reason": ctx.Err().Error()})
+		return ctx.Err()
+	case <-c.done:
+		return c.err
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result == nil {
+			return nil
+		}
+		return json.Unmarshal(resp.Result, result)
+	}
+}
+
+func (c *Client) notify(method string, params any) error {
+	return c.send(&message{Method: method}, params)
+}
+
+func (c *Client) send(msg *message, params any) error {
+	msg.JSONRPC = "2.0"
+	if params != nil {
+		p, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		msg.Params = p
+	}
+	return writeMessage(&c.writeMu, c.stdin, msg)
+}
+
+func (c *Client) readLoop(r io.Reader) {
+	err := readMessages(r, func(msg *message) {
+		if msg.isResponse() {
+			var id int64
+			if json.Unmarshal(*msg.ID, &id) != nil {
+				return
+			}
+			c.mu.Lock()
+			ch := c.pending[id]
+			c.mu.Unlock()
+			if ch != nil {
+				ch <- msg
+			}
+			return
+		}
+		if msg.ID == nil {
+			// notifications such as logging and progress are ignored
+			return
+		}
+		// Requests from the server. Only ping is supported since the
+		// client advertises no capabilities.
+		resp := &message{JSONRPC: "2.0", ID: msg.ID}
+		if msg.Method == "ping" {
+			resp.Result = json.RawMessage("{}")
+		} else {
+			resp.Error = &RPCError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method}
+		}
+		writeMessage(&c.writeMu, c.stdin, resp)
+	})
+
+	if err == nil {
+		err = io.EOF
+	}
+	err = fmt.Errorf("server exited: %w", err)
+	if tail := strings.TrimSpace(c.stderr.String()); tail != "" {
+		err = fmt.Errorf("%w: %s", err, tail)
+	}
+	c.mu.Lock()
+	c.err = err
+	c.mu.Unlock()
+	close(c.done)
+}
+
+// readMessages calls fn with each message read from r until it ends.
+// Lines that aren't valid JSON are skipped.
+func readMessages(r io.Reader, fn func(*message)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		var msg message
+		if err := json.Unmarshal(line, &msg); err != nil {
+			continue
+		}
+		fn(&msg)
+	}
+	return scanner.Err()
+}
+
+func writeMessage(mu *sync.Mutex, w io.Writer, msg *message) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// tailBuffer keeps the last max bytes written to it.
+type tailBuffer struct {
+	mu  sync.Mutex
+	max int
+	buf []byte
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > t.max {
+		t.buf = t.buf[len(t.buf)-t.max:]
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return string(t.buf)
+}
@@ -0,0 +1,132 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestHelperServer is run as a subprocess by TestClient to act as a
+// minimal MCP server.
+func TestHelperServer(t *testing.T) {
+	if os.Getenv("MCP_TEST_HELPER_SERVER") != "1" {
+		t.Skip("helper process")
+	}
+	var mu sync.Mutex
+	reply := func(msg *message, result any) {
+		b, _ := json.Marshal(result)
+		writeMessage(&mu, os.Stdout, &message{JSONRPC: "2.0", ID: msg.ID, Result: b})
+	}
+	readMessages(os.Stdin, func(msg *message) {
+		switch msg.Method {
+		case "initialize":
+			reply(msg, initializeResult{
+				ProtocolVersion: ProtocolVersion,
+				ServerInfo:      Implementation{Name: "helper", Version: "1"},
+			})
+		case "tools/list":
+			var p listToolsParams
+			json.Unmarshal(msg.Params, &p)
+			// two pages to exercise the cursor
+			if p.Cursor == "" {
+				// raw JSON, since Schema can't encode the nullable and
+				// tuple forms real servers send
+				reply(msg, json.RawMessage(`{"tools": [{"name": "echo", "inputSchema": {"type": "object", "properties": {
+					"n": {"type": ["null", "integer"]},
+					"tags": {"type": "array", "items": [{"type": ["string", "null"]}]},
+					"extra": true
+				}}}], "nextCursor": "2"}`))
+			} else {
+				reply(msg, listToolsResult{Tools: []Tool{{Name: "fail"}}})
+			}
+		case "tools/call":
+			var p callToolParams
+			json.Unmarshal(msg.Params, &p)
+			if p.Name == "fail" {
+				reply(msg, CallToolResult{Content: []Content{TextContent("it failed")}, IsError: true})
+				return
+			}
+			b, _ := json.Marshal(p.Arguments)
+			reply(msg, CallToolResult{Content: []Content{TextContent(string(b))}})
+		case "crash":
+			os.Stderr.WriteString("crashing on purpose\n")
+			os.Exit(1)
+		default:
+			if msg.ID != nil {
+				writeMessage(&mu, os.Stdout, &message{JSONRPC: "2.0", ID: msg.ID, Error: &RPCError{Code: codeMethodNotFound, Message: "not found"}})
+			}
+		}
+	})
+	os.Exit(0)
+}
+
+func TestClient(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	c, err := Start(ctx, ServerParams{
+		Command: os.Args[0],
+		Args:    []string{"-test.run=^TestHelperServer$"},
+		Env:     []string{"MCP_TEST_HELPER_SERVER=1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if c.ServerInfo.Name != "helper" {
+		t.Errorf("ServerInfo = %+v", c.ServerInfo)
+	}
+
+	tools, err := c.ListTools(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tools) != 2 || tools[0].Name != "echo" || tools[1].Name != "fail" {
+		t.Fatalf("ListTools = %+v", tools)
+	}
+	props := tools[0].InputSchema.Properties
+	if props["n"].Type != "integer" {
+		t.Errorf("nullable type = %q, want integer", props["n"].Type)
+	}
+	if props["tags"].Items == nil || props["tags"].Items.Type != "string" {
+		t.Errorf("tuple items = %+v, want string items", props["tags"].Items)
+	}
+	if props["extra"] == nil || props["extra"].Type != "" {
+		t.Errorf("boolean schema = %+v", props["extra"])
+	}
+
+	res, err := c.CallTool(ctx, "echo", map[string]any{"n": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.IsError || len(res.Content) != 1 || res.Content[0].Text != `{"n":1}` {
+		t.Errorf("CallTool echo = %+v", res)
+	}
+
+	res, err = c.CallTool(ctx, "fail", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.IsError {
+		t.Errorf("CallTool fail = %+v, want IsError", res)
+	}
+
+	var rpcErr *RPCError
+	if err := c.call(ctx, "no/such/method", nil, nil); err == nil || !errors.As(err, &rpcErr) || rpcErr.Code != codeMethodNotFound {
+		t.Errorf("unknown method err = %v", err)
+	}
+
+	err = c.call(ctx, "crash", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "server exited") {
+		t.Errorf("crash err = %v", err)
+	}
+	if _, err := c.ListTools(ctx); err == nil {
+		t.Errorf("ListTools after crash: expected error")
+	}
+}
@@ -0,0 +1,174 @@
+// Package mcp implements the parts of the Model Context Protocol used by
+// code-buddy: a client for tools provided by MCP servers, and a server
+// exposing code-buddy's own tools. Messages are JSON-RPC 2.0, one per
+// line, over stdio.
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// ProtocolVersion is the MCP revision implemented by this package.
+const ProtocolVersion = "2024-11-05"
+
+// JSON-RPC error codes.
+const (
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+// message is a JSON-RPC request, notification or response. Requests and
+// responses have an ID, notifications don't.
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *RPCError        `json:"error,omitempty"`
+}
+
+func (m *message) isResponse() bool {
+	return m.ID != nil && m.Method == ""
+}
+
+// RPCError is an error returned by the other side of the connection.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+type Implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type initializeParams struct {
+	ProtocolVersion string         `json:"protocolVersion"`
+	Capabilities    map[string]any `json:"capabilities"`
+	ClientInfo      Implementation `json:"clientInfo"`
+}
+
+type initializeResult struct {
+	ProtocolVersion string         `json:"protocolVersion"`
+	Capabilities    map[string]any `json:"capabilities"`
+	ServerInfo      Implementation `json:"serverInfo"`
+	Instructions    string         `json:"instructions,omitempty"`
+}
+
+// Schema is the subset of JSON Schema used to describe tool inputs.
+type Schema struct {
+	Type        string             `json:"type,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Enum        []any              `json:"enum,omitempty"`
+}
+
+// UnmarshalJSON accepts the forms of JSON Schema that servers use but
+// Schema doesn't model, rather than failing to decode the whole tool list.
+// A list of types, such as ["string", "null"] for a nullable field, uses
+// the first type that isn't "null", a list of item schemas uses the first
+// schema, and boolean schemas decode as an empty Schema.
+func (s *Schema) UnmarshalJSON(b []byte) error {
+	if v := string(bytes.TrimSpace(b)); v == "true" || v == "false" {
+		*s = Schema{}
+		return nil
+	}
+
+	type plain Schema
+	var raw struct {
+		plain
+		Type  json.RawMessage `json:"type"`
+		Items json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*s = Schema(raw.plain)
+
+	if len(raw.Type) > 0 && string(raw.Type) != "null" {
+		var types []string
+		if raw.Type[0] == '[' {
+			if err := json.Unmarshal(raw.Type, &types); err != nil {
+				return fmt.Errorf("schema type: %w", err)
+			}
+		} else {
+			var t string
+			if err := json.Unmarshal(raw.Type, &t); err != nil {
+				return fmt.Errorf("schema type: %w", err)
+			}
+			types = []string{t}
+		}
+		for _, t := range types {
+			if t != "null" {
+				s.Type = t
+				break
+			}
+		}
+	}
+
+	if len(raw.Items) > 0 && string(raw.Items) != "null" {
+		if raw.Items[0] == '[' {
+			var items []*Schema
+			if err := json.Unmarshal(raw.Items, &items); err != nil {
+				return fmt.Errorf("schema items: %w", err)
+			}
+			if len(items) > 0 {
+				s.Items = items[0]
+			}
+		} else {
+			s.Items = new(Schema)
+			if err := json.Unmarshal(raw.Items, s.Items); err != nil {
+				return fmt.Errorf("schema items: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+type Tool struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	InputSchema Schema `json:"inputSchema"`
+}
+
+type listToolsParams struct {
+	Cursor string `json:"cursor,omitempty"`
+}
+
+type listToolsResult struct {
+	Tools      []Tool `json:"tools"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+type callToolParams struct {
+	Name      string         `json:"name"`
+	Arguments map[string]any `json:"arguments,omitempty"`
+}
+
+// Content is one item of a tool result. Only text content is produced by
+// code-buddy; other types are passed through as their type and MIME type.
+type Content struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	Data     string `json:"data,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+func TextContent(text string) Content {
+	return Content{Type: "text", Text: text}
+}
+
+type CallToolResult struct {
+	Content []Content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
@@ -0,0 +1,96 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ToolHandler runs a tool call. Returning an error reports it to the
+// client as a failed tool result.
+type ToolHandler func(ctx context.Context, args map[string]any) (string, error)
+
+// Server is an MCP server providing tools.
+type Server struct {
+	Info         Implementation
+	Instructions string
+
+	tools    []Tool
+	handlers map[string]ToolHandler
+}
+
+func NewServer(name, version string) *Server {
+	return &Server{
+		Info:     Implementation{Name: name, Version: version},
+		handlers: make(map[string]ToolHandler),
+	}
+}
+
+func (s *Server) AddTool(t Tool, h ToolHandler) {
+	s.tools = append(s.tools, t)
+	s.handlers[t.Name] = h
+}
+
+// Serve reads requests from r and writes responses to w until r is
+// closed. Requests are handled one at a time, so tools that modify files
+// never run concurrently.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	var mu sync.Mutex
+	return readMessages(r, func(msg *message) {
+		if msg.ID == nil || msg.isResponse() {
+			// notifications, and responses to requests we never send
+			return
+		}
+		resp := &message{JSONRPC: "2.0", ID: msg.ID}
+		result, err := s.handle(ctx, msg)
+		if err != nil {
+			rpcErr, ok := err.(*RPCError)
+			if !ok {
+				rpcErr = &RPCError{Code: codeInternalError, Message: err.Error()}
+			}
+			resp.Error = rpcErr
+		} else {
+			b, err := json.Marshal(result)
+			if err != nil {
+				resp.Error = &RPCError{Code: codeInternalError, Message: err.Error()}
+			} else {
+				resp.Result = b
+			}
+		}
+		writeMessage(&mu, w, resp)
+	})
+}
+
+func (s *Server) handle(ctx context.Context, msg *message) (any, error) {
+	switch msg.Method {
+	case "initialize":
+		return initializeResult{
+			ProtocolVersion: ProtocolVersion,
+			Capabilities:    map[string]any{"tools": map[string]any{}},
+			ServerInfo:      s.Info,
+			Instructions:    s.Instructions,
+		}, nil
+	case "ping":
+		return struct{}{}, nil
+	case "tools/list":
+		return listToolsResult{Tools: s.tools}, nil
+	case "tools/call":
+		var params callToolParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &RPCError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		h := s.handlers[params.Name]
+		if h == nil {
+			return nil, &RPCError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown tool %q", params.Name)}
+		}
+		out, err := h(ctx, params.Arguments)
+		if err != nil {
+			return CallToolResult{Content: []Content{TextContent(err.Error())}, IsError: true}, nil
+		}
+		return CallToolResult{Content: []Content{TextContent(out)}}, nil
+	default:
+		return nil, &RPCError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method}
+	}
+}
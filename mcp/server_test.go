@@ -0,0 +1,78 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	s := NewServer("test", "1")
+	s.AddTool(Tool{Name: "upper", InputSchema: Schema{Type: "object"}}, func(ctx context.Context, args map[string]any) (string, error) {
+		text, _ := args["text"].(string)
+		if text == "" {
+			return "", errors.New("text is required")
+		}
+		return strings.ToUpper(text), nil
+	})
+
+	in := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`not json`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"upper","arguments":{"text":"hi"}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"upper","arguments":{}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"missing"}}`,
+		`{"jsonrpc":"2.0","id":"six","method":"resources/list"}`,
+	}, "\n")
+	var out strings.Builder
+	if err := s.Serve(context.Background(), strings.NewReader(in), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	responses := make(map[string]*message)
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var msg message
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			t.Fatalf("bad response %q: %s", line, err)
+		}
+		responses[string(*msg.ID)] = &msg
+	}
+	if len(responses) != 6 {
+		t.Fatalf("got %d responses, want 6:\n%s", len(responses), out.String())
+	}
+
+	var init initializeResult
+	json.Unmarshal(responses["1"].Result, &init)
+	if init.ProtocolVersion != ProtocolVersion || init.ServerInfo.Name != "test" || init.Capabilities["tools"] == nil {
+		t.Errorf("initialize = %s", responses["1"].Result)
+	}
+
+	var list listToolsResult
+	json.Unmarshal(responses["2"].Result, &list)
+	if len(list.Tools) != 1 || list.Tools[0].Name != "upper" {
+		t.Errorf("tools/list = %s", responses["2"].Result)
+	}
+
+	var res CallToolResult
+	json.Unmarshal(responses["3"].Result, &res)
+	if res.IsError || len(res.Content) != 1 || res.Content[0].Text != "HI" {
+		t.Errorf("tools/call = %s", responses["3"].Result)
+	}
+
+	res = CallToolResult{}
+	json.Unmarshal(responses["4"].Result, &res)
+	if !res.IsError || res.Content[0].Text != "text is required" {
+		t.Errorf("failing tools/call = %s", responses["4"].Result)
+	}
+
+	if e := responses["5"].Error; e == nil || e.Code != codeInvalidParams {
+		t.Errorf("unknown tool error = %+v", e)
+	}
+	if e := responses[`"six"`].Error; e == nil || e.Code != codeMethodNotFound {
+		t.Errorf("unknown method error = %+v", e)
+	}
+}
@@ -0,0 +1,57 @@
+package repomap
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"strings"
+
+	"github.com/psanford/code-buddy/fswalk"
+)
+
+// Cache holds the most recently built map for a module and rebuilds it
+// only when a Go file has been added, removed or modified.
+type Cache struct {
+	Root string
+	Opts Options
+
+	fingerprint uint64
+	repoMap     string
+}
+
+// String returns the current map, rebuilding it if files have changed.
+func (c *Cache) String() (string, error) {
+	fp, err := fingerprint(c.Root)
+	if err != nil {
+		return "", err
+	}
+	if fp == c.fingerprint && c.repoMap != "" {
+		return c.repoMap, nil
+	}
+
+	m, err := Build(c.Root, c.Opts)
+	if err != nil {
+		return "", err
+	}
+	c.fingerprint = fp
+	c.repoMap = m
+	return m, nil
+}
+
+// fingerprint hashes the path, size and modification time of every Go
+// file below root.
+func fingerprint(root string) (uint64, error) {
+	h := fnv.New64a()
+	err := fswalk.Walk(root, func(rel string, d fs.DirEntry) error {
+		if d.IsDir() || !strings.HasSuffix(rel, ".go") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00%d\n", rel, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	return h.Sum64(), err
+}
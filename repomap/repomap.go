@@ -0,0 +1,361 @@
+// Package repomap builds a compact summary of a Go module's exported API:
+// its packages, types, functions and method signatures. The summary is
+// ranked so the most widely imported packages come first and is trimmed
+// to fit a token budget.
+package repomap
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/psanford/code-buddy/fswalk"
+)
+
+// DefaultTokenBudget is used when Options.TokenBudget is not set.
+const DefaultTokenBudget = 2000
+
+type Options struct {
+	// TokenBudget is an approximate limit on the size of the map,
+	// estimated at 4 bytes per token.
+	TokenBudget int
+}
+
+// IsGoModule reports whether root contains a go.mod file.
+func IsGoModule(root string) bool {
+	_, err := os.Stat(filepath.Join(root, "go.mod"))
+	return err == nil
+}
+
+type pkgInfo struct {
+	dir        string // slash separated, relative to the module root
+	name       string
+	importPath string
+	imports    map[string]bool
+	importedBy int
+
+	types []*typeInfo
+	funcs []string
+	// methods on types declared in another file of the package,
+	// keyed by receiver type name
+	methods map[string][]string
+}
+
+type typeInfo struct {
+	name    string
+	kind    string
+	methods []string
+}
+
+func (p *pkgInfo) typeByName(name string) *typeInfo {
+	for _, t := range p.types {
+		if t.name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// Build parses every non-test Go file below root and renders the map.
+func Build(root string, opts Options) (string, error) {
+	modPath := modulePath(root)
+
+	pkgs := make(map[string]*pkgInfo)
+	fset := token.NewFileSet()
+
+	err := fswalk.Walk(root, func(rel string, d fs.DirEntry) error {
+		if d.IsDir() {
+			if d.Name() == "testdata" || d.Name() == "vendor" {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(rel, ".go") || strings.HasSuffix(rel, "_test.go") {
+			return nil
+		}
+
+		f, err := parser.ParseFile(fset, filepath.Join(root, filepath.FromSlash(rel)), nil, parser.SkipObjectResolution)
+		if err != nil {
+			// Skip files that don't parse; the map is best effort.
+			return nil
+		}
+
+		dir := path.Dir(rel)
+		p := pkgs[dir]
+		if p == nil {
+			p = &pkgInfo{
+				dir:     dir,
+				name:    f.Name.Name,
+				imports: make(map[string]bool),
+				methods: make(map[string][]string),
+			}
+			if modPath != "" {
+				p.importPath = modPath
+				if dir != "." {
+					p.importPath = modPath + "/" + dir
+				}
+			}
+			pkgs[dir] = p
+		}
+
+		for _, imp := range f.Imports {
+			ip, _ := strconv.Unquote(imp.Path.Value)
+			p.imports[ip] = true
+		}
+
+		collectDecls(fset, f, p)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return render(pkgs, opts), nil
+}
+
+func modulePath(root string) string {
+	f, err := os.Open(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "module ") {
+			return strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "module ")), `"`)
+		}
+	}
+	return ""
+}
+
+func collectDecls(fset *token.FileSet, f *ast.File, p *pkgInfo) {
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			if d.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range d.Specs {
+				ts := spec.(*ast.TypeSpec)
+				if !ts.Name.IsExported() {
+					continue
+				}
+				t := &typeInfo{name: ts.Name.Name, kind: typeKind(ts)}
+				t.methods = p.methods[t.name]
+				delete(p.methods, t.name)
+				p.types = append(p.types, t)
+			}
+		case *ast.FuncDecl:
+			if !d.Name.IsExported() {
+				continue
+			}
+			sig := funcSignature(fset, d)
+			if d.Recv == nil || len(d.Recv.List) == 0 {
+				p.funcs = append(p.funcs, sig)
+				continue
+			}
+			recv := receiverName(d.Recv.List[0].Type)
+			if !ast.IsExported(recv) {
+				continue
+			}
+			if t := p.typeByName(recv); t != nil {
+				t.methods = append(t.methods, sig)
+			} else {
+				p.methods[recv] = append(p.methods[recv], sig)
+			}
+		}
+	}
+}
+
+func typeKind(ts *ast.TypeSpec) string {
+	if ts.Assign.IsValid() {
+		return "alias"
+	}
+	switch ts.Type.(type) {
+	case *ast.StructType:
+		return "struct"
+	case *ast.InterfaceType:
+		return "interface"
+	case *ast.FuncType:
+		return "func"
+	case *ast.MapType:
+		return "map"
+	case *ast.ArrayType:
+		return "slice"
+	}
+	return "type"
+}
+
+func receiverName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(t.X)
+	case *ast.IndexExpr:
+		return receiverName(t.X)
+	case *ast.IndexListExpr:
+		return receiverName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+func funcSignature(fset *token.FileSet, d *ast.FuncDecl) string {
+	stripped := *d
+	stripped.Body = nil
+	stripped.Doc = nil
+
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, &stripped); err != nil {
+		return "func " + d.Name.Name
+	}
+	// collapse multi-line parameter lists
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
+
+func render(pkgs map[string]*pkgInfo, opts Options) string {
+	byImportPath := make(map[string]*pkgInfo)
+	for _, p := range pkgs {
+		if p.importPath != "" {
+			byImportPath[p.importPath] = p
+		}
+	}
+	for _, p := range pkgs {
+		for ip := range p.imports {
+			if dep := byImportPath[ip]; dep != nil && dep != p {
+				dep.importedBy++
+			}
+		}
+		// methods whose receiver type wasn't found are listed as funcs
+		recvs := make([]string, 0, len(p.methods))
+		for recv := range p.methods {
+			recvs = append(recvs, recv)
+		}
+		sort.Strings(recvs)
+		for _, recv := range recvs {
+			p.funcs = append(p.funcs, p.methods[recv]...)
+		}
+	}
+
+	ranked := make([]*pkgInfo, 0, len(pkgs))
+	for _, p := range pkgs {
+		if len(p.types) > 0 || len(p.funcs) > 0 {
+			ranked = append(ranked, p)
+		}
+	}
+	// Packages imported by many others are the most useful to know about.
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].importedBy != ranked[j].importedBy {
+			return ranked[i].importedBy > ranked[j].importedBy
+		}
+		return ranked[i].dir < ranked[j].dir
+	})
+
+	budget := opts.TokenBudget
+	if budget <= 0 {
+		budget = DefaultTokenBudget
+	}
+	budget *= 4
+
+	var (
+		buf     strings.Builder
+		omitted []string
+	)
+	for _, p := range ranked {
+		full := renderPackage(p, false)
+		if buf.Len()+len(full) <= budget {
+			buf.WriteString(full)
+			continue
+		}
+		short := renderPackage(p, true)
+		if buf.Len()+len(short) <= budget {
+			buf.WriteString(short)
+			continue
+		}
+		omitted = append(omitted, p.dir)
+	}
+	if len(omitted) > 0 {
+		buf.WriteString(omittedLine(omitted, budget-buf.Len()))
+	}
+
+	return buf.String()
+}
+
+// omittedLine reports the packages left out of the map, naming as many
+// as fit in room bytes.
+func omittedLine(omitted []string, room int) string {
+	line := fmt.Sprintf("… %d more packages omitted", len(omitted))
+	for i, dir := range omitted {
+		sep := ", "
+		if i == 0 {
+			sep = ": "
+		}
+		if len(line)+len(sep)+len(dir)+1 > room {
+			break
+		}
+		line += sep + dir
+	}
+	return line + "\n"
+}
+
+// renderPackage renders one package. The short form lists only type
+// and function names without signatures.
+func renderPackage(p *pkgInfo, short bool) string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "package %s (%s/)", p.name, p.dir)
+	if p.importedBy > 0 {
+		fmt.Fprintf(&buf, " imported by %d", p.importedBy)
+	}
+	buf.WriteString("\n")
+
+	if short {
+		var names []string
+		for _, t := range p.types {
+			names = append(names, t.name)
+		}
+		for _, f := range p.funcs {
+			names = append(names, funcName(f))
+		}
+		fmt.Fprintf(&buf, "  %s\n", strings.Join(names, ", "))
+		return buf.String()
+	}
+
+	for _, t := range p.types {
+		fmt.Fprintf(&buf, "  type %s %s\n", t.name, t.kind)
+		for _, m := range t.methods {
+			fmt.Fprintf(&buf, "    %s\n", m)
+		}
+	}
+	for _, f := range p.funcs {
+		fmt.Fprintf(&buf, "  %s\n", f)
+	}
+	return buf.String()
+}
+
+// funcName extracts the name from a rendered signature such as
+// "func (r *T) Name(x int) error".
+func funcName(sig string) string {
+	s := strings.TrimPrefix(sig, "func ")
+	if strings.HasPrefix(s, "(") {
+		if idx := strings.Index(s, ") "); idx >= 0 {
+			s = s[idx+2:]
+		}
+	}
+	if idx := strings.IndexAny(s, "(["); idx >= 0 {
+		s = s[:idx]
+	}
+	return s
+}
@@ -0,0 +1,148 @@
+package repomap
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.22\n",
+		"main.go": `package main
+
+import "example.com/m/store"
+
+func main() { store.Open("") }
+`,
+		"store/methods.go": `package store
+
+func (s *Store) Get(key string) ([]byte, error) { return nil, nil }
+
+func (s *Store) put(key string) {}
+`,
+		"store/store.go": `package store
+
+type Store struct{ path string }
+
+type Getter interface {
+	Get(key string) ([]byte, error)
+}
+
+type internal struct{}
+
+func Open(path string,
+	readOnly bool) (*Store, error) {
+	return nil, nil
+}
+`,
+		"store/store_test.go": "package store\n\nfunc TestHelper() {}\n",
+		"api/api.go":          "package api\n\nimport \"example.com/m/store\"\n\nvar _ store.Getter\n\nfunc Serve() {}\n",
+	})
+
+	got, err := Build(root, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := `package store (store/) imported by 2
+  type Store struct
+    func (s *Store) Get(key string) ([]byte, error)
+  type Getter interface
+  func Open(path string, readOnly bool) (*Store, error)
+package api (api/)
+  func Serve()
+`
+	if got != expect {
+		t.Fatalf("got:\n%s\nexpected:\n%s", got, expect)
+	}
+
+	// A tiny budget falls back to names only, then omits packages.
+	got, err = Build(root, Options{TokenBudget: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, "  Store, Getter, Open\n") || !strings.HasSuffix(got, "… 1 more packages omitted\n") {
+		t.Fatalf("unexpected trimmed map:\n%s", got)
+	}
+}
+
+func TestOmittedLine(t *testing.T) {
+	omitted := []string{"api", "internal/db", "cmd"}
+	if got := omittedLine(omitted, 1000); got != "… 3 more packages omitted: api, internal/db, cmd\n" {
+		t.Errorf("got %q", got)
+	}
+	if got := omittedLine(omitted, 40); got != "… 3 more packages omitted: api\n" {
+		t.Errorf("got %q", got)
+	}
+	if got := omittedLine(omitted, 0); got != "… 3 more packages omitted\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestOrphanMethodsSorted(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"a/a.go":      "package a\n\nfunc (Zeta) Z() {}\n\nfunc (Alpha) A() {}\n\nfunc (Mid) M() {}\n",
+		"a/a_test.go": "package a\n\ntype Zeta int\ntype Alpha int\ntype Mid int\n",
+	})
+
+	expect := "package a (a/)\n  func (Alpha) A()\n  func (Mid) M()\n  func (Zeta) Z()\n"
+	for i := 0; i < 10; i++ {
+		got, err := Build(root, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != expect {
+			t.Fatalf("got:\n%s\nexpected:\n%s", got, expect)
+		}
+	}
+}
+
+func TestCacheRefresh(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"go.mod": "module example.com/m\n",
+		"a/a.go": "package a\n\nfunc A() {}\n",
+	})
+
+	c := Cache{Root: root}
+	got, err := c.String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(got, "func B()") {
+		t.Fatalf("unexpected B in map:\n%s", got)
+	}
+
+	p := filepath.Join(root, "a", "a.go")
+	writeFiles(t, root, map[string]string{"a/a.go": "package a\n\nfunc A() {}\n\nfunc B() {}\n"})
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(p, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err = c.String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, "func B()") {
+		t.Fatalf("expected map to be rebuilt with B:\n%s", got)
+	}
+}